@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
 	// "log"
 	"product-management-system/internal/cache"
 	"product-management-system/internal/config"
@@ -11,10 +15,8 @@ import (
 	"product-management-system/internal/repository"
 	"product-management-system/internal/service"
 	"product-management-system/pkg/logger"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"product-management-system/pkg/money"
+	"product-management-system/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
@@ -29,49 +31,122 @@ func main() {
 	appLogger := logger.NewLogger()
 
 	// Database connection
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=require",
-		cfg.Database.Host, cfg.Database.Port,
-		cfg.Database.User, cfg.Database.Password,
-		cfg.Database.DBName)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseDSN()), &gorm.Config{})
 	if err != nil {
 		appLogger.Fatal("Failed to connect to database", "error", err)
 	}
 
 	// Run database migrations
-	if err := db.AutoMigrate(&models.User{}, &models.Product{}); err != nil {
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Category{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.StockReservation{},
+		&models.PriceChange{},
+		&models.PriceSchedule{},
+		&models.PriceListEntry{},
+		&models.OutboxMessage{},
+	); err != nil {
 		appLogger.Fatal("Failed to run migrations", "error", err)
 	}
+	if err := repository.MigrateMoney(db, cfg.Currency.Default); err != nil {
+		appLogger.Fatal("Failed to migrate prices to money", "error", err)
+	}
+	if err := repository.MigrateProductSearch(db); err != nil {
+		appLogger.Fatal("Failed to create product search index", "error", err)
+	}
+
+	// Load exchange rates
+	rates, err := money.NewRates(cfg.Currency.Default, nil)
+	if cfg.Currency.RatesFile != "" {
+		rates, err = money.LoadRates(cfg.Currency.RatesFile)
+	}
+	if err != nil {
+		appLogger.Fatal("Failed to load exchange rates", "error", err)
+	}
+	if !rates.Supports(cfg.Currency.Default) {
+		appLogger.Fatal("Exchange rates don't cover the default currency", "currency", cfg.Currency.Default)
+	}
 
 	// Initialize Redis Cache
 	redisCache := cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.User, cfg.Redis.Password)
 
 	// Initialize Repositories
 	productRepo := repository.NewProductRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
 
-	// Initialize Message Queue
-	messageQueue := queue.NewRabbitMQQueue(cfg.RabbitMQ.Host, cfg.RabbitMQ.Port)
+	// Initialize Task Queue. RabbitMQ connects in the background; tasks
+	// wait in the outbox while the broker is unreachable
+	taskQueue, err := queue.New(cfg.QueueConfig(db), appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize task queue", "error", err)
+	}
 
-	// Initialize AWS Session
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(cfg.AWS.Region),
+	// Initialize Blob Store
+	blobStore, err := service.NewBlobStore(service.BlobStoreConfig{
+		Backend:        cfg.Storage.Backend,
+		Bucket:         cfg.AWS.S3Bucket,
+		Region:         cfg.AWS.Region,
+		Endpoint:       cfg.AWS.Endpoint,
+		ForcePathStyle: cfg.AWS.S3ForcePathStyle,
+		LocalDir:       cfg.Storage.Local.Dir,
+		PublicBaseURL:  cfg.Storage.PublicBaseURL,
 	})
 	if err != nil {
-		appLogger.Fatal("Failed to initialize AWS session", "error", err)
+		appLogger.Fatal("Failed to initialize blob store", "error", err)
 	}
 
-	// Initialize S3 Client
-	s3Client := s3.New(sess)
-
 	// Initialize Image Processor
-	imageProcessor := service.NewImageProcessor(s3Client, cfg.AWS.S3Bucket, appLogger)
+	fetcher, err := utils.NewFetcher(cfg.Fetch)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize image fetcher", "error", err)
+	}
+	imageProcessor := service.NewImageProcessor(blobStore, fetcher, cfg.Images.Variants, cfg.Worker.MaxImagesPerTask, appLogger)
 
 	// Initialize Services
 	productService := service.NewProductService(
 		productRepo,
+		categoryRepo,
 		imageProcessor,
-		messageQueue,
+		blobStore,
+		service.UploadLimits{
+			MaxBytes:      cfg.Images.Uploads.MaxBytes,
+			MaxWidth:      cfg.Images.Uploads.MaxWidth,
+			MaxHeight:     cfg.Images.Uploads.MaxHeight,
+			PresignExpiry: cfg.Images.Uploads.PresignExpiry,
+		},
+		service.CurrencySettings{
+			Default:  cfg.Currency.Default,
+			Rates:    rates,
+			Rounding: money.Rounding(cfg.Currency.Rounding),
+		},
+		appLogger,
+	)
+
+	categoryService := service.NewCategoryService(categoryRepo, appLogger)
+	outboxRelay := service.NewOutboxRelay(
+		outboxRepo,
+		taskQueue,
+		service.OutboxSettings{
+			Interval:   cfg.Outbox.RelayInterval,
+			BatchSize:  cfg.Outbox.BatchSize,
+			Lease:      cfg.Outbox.Lease,
+			MaxBackoff: cfg.Outbox.MaxBackoff,
+			Retention:  cfg.Outbox.Retention,
+		},
+		appLogger,
+	)
+	inventoryService := service.NewInventoryService(productRepo, productService, cfg.Inventory.ReservationTTL, appLogger)
+
+	authService := service.NewAuthService(
+		userRepo,
+		redisCache,
+		cfg.JWT.Secret,
+		cfg.JWT.AccessTokenTTL,
+		cfg.JWT.RefreshTokenTTL,
 		appLogger,
 	)
 
@@ -81,25 +156,179 @@ func main() {
 		redisCache,
 		appLogger,
 	)
+	authHandler := handlers.NewAuthHandler(authService, appLogger)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, appLogger)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService, redisCache, appLogger)
+	adminHandler := handlers.NewAdminHandler(taskQueue, appLogger)
+	requireAuth := handlers.RequireAuth(authService)
+	optionalAuth := handlers.OptionalAuth(authService)
 
 	// Setup Gin Router
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
-	// Product Routes
+	// Serve images written by the local blob store
+	if localStore, ok := blobStore.(*service.LocalBlobStore); ok {
+		router.Static(service.DefaultLocalBlobURLPrefix, localStore.Root())
+	}
+
 	v1 := router.Group("/api/v1")
+
+	// Auth Routes
+	auth := v1.Group("/auth")
 	{
-		v1.POST("/products", productHandler.CreateProduct)
-		v1.GET("/products/:id", productHandler.GetProductByID)
-		v1.GET("/products", productHandler.ListProducts)
+		auth.POST("/register", authHandler.Register)
+		auth.POST("/login", authHandler.Login)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/logout", requireAuth, authHandler.Logout)
+	}
+
+	// Product Routes
+	{
+		v1.POST("/products", requireAuth, productHandler.CreateProduct)
+		v1.GET("/products/search", optionalAuth, productHandler.SearchProducts)
+		v1.GET("/products/:id", optionalAuth, productHandler.GetProductByID)
+		v1.GET("/products/:id/images/status", optionalAuth, productHandler.GetImageStatus)
+		v1.POST("/products/:id/images", requireAuth, productHandler.UploadImage)
+		v1.POST("/products/:id/images/uploads", requireAuth, productHandler.CreateUploadSlot)
+		v1.POST("/products/:id/images/uploads/:upload_id/confirm", requireAuth, productHandler.ConfirmUpload)
+		v1.GET("/products/:id/variants", optionalAuth, productHandler.ListVariants)
+		v1.GET("/products/:id/variants/:variant_id", optionalAuth, productHandler.GetVariant)
+		v1.POST("/products/:id/variants", requireAuth, productHandler.CreateVariant)
+		v1.PUT("/products/:id/variants/:variant_id", requireAuth, productHandler.UpdateVariant)
+		v1.DELETE("/products/:id/variants/:variant_id", requireAuth, productHandler.DeleteVariant)
+		v1.GET("/products/:id/prices", optionalAuth, productHandler.GetPrices)
+		v1.POST("/products/:id/prices/schedules", requireAuth, productHandler.SchedulePrice)
+		v1.DELETE("/products/:id/prices/schedules/:schedule_id", requireAuth, productHandler.CancelPriceSchedule)
+		v1.GET("/products/:id/price-list", optionalAuth, productHandler.GetPriceList)
+		v1.PUT("/products/:id/price-list/:currency", requireAuth, productHandler.SetListPrice)
+		v1.DELETE("/products/:id/price-list/:currency", requireAuth, productHandler.DeleteListPrice)
+		v1.GET("/products", optionalAuth, productHandler.ListProducts)
+		v1.PUT("/products/:id", requireAuth, productHandler.UpdateProduct)
+		v1.PATCH("/products/:id", requireAuth, productHandler.PatchProduct)
+		v1.DELETE("/products/:id", requireAuth, productHandler.DeleteProduct)
+	}
+
+	// Category Routes
+	{
+		requireAdmin := handlers.RequireRole(models.RoleAdmin)
+		v1.GET("/categories", categoryHandler.ListCategories)
+		v1.GET("/categories/:id", categoryHandler.GetCategory)
+		v1.POST("/categories", requireAuth, requireAdmin, categoryHandler.CreateCategory)
+		v1.PUT("/categories/:id", requireAuth, requireAdmin, categoryHandler.UpdateCategory)
+		v1.DELETE("/categories/:id", requireAuth, requireAdmin, categoryHandler.DeleteCategory)
+	}
+
+	// Reservation Routes
+	reservations := v1.Group("/reservations", requireAuth)
+	{
+		reservations.POST("", inventoryHandler.Reserve)
+		reservations.GET("/:id", inventoryHandler.GetReservation)
+		reservations.POST("/:id/commit", inventoryHandler.CommitReservation)
+		reservations.POST("/:id/release", inventoryHandler.ReleaseReservation)
+	}
+
+	// Admin Routes
+	admin := v1.Group("/admin", requireAuth, handlers.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/dlq", adminHandler.ListDeadLetters)
+		admin.POST("/dlq/replay", adminHandler.ReplayDeadLetters)
+		admin.POST("/dlq/purge", adminHandler.PurgeDeadLetters)
 	}
 
 	// Start the server
 	serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	appLogger.Info("Starting server", "address", serverAddr)
+	server := &http.Server{
+		Addr:    serverAddr,
+		Handler: router,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		appLogger.Info("Starting server", "address", serverAddr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Release stock held by reservations that were never committed
+	expiryDone := make(chan struct{})
+	go func() {
+		defer close(expiryDone)
+		inventoryService.RunReservationExpiry(ctx, cfg.Inventory.ExpiryInterval)
+	}()
 
-	if err := router.Run(serverAddr); err != nil {
+	// Publish image processing tasks written to the outbox
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		outboxRelay.Run(ctx)
+	}()
+
+	// The in-memory queue is only reachable from this process, so the API
+	// processes its images itself
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		if cfg.Queue.Backend != queue.BackendMemory {
+			return
+		}
+		worker := service.NewImageWorker(productRepo, imageProcessor, appLogger)
+		err := service.RunImageWorkers(ctx, taskQueue, worker, service.ImageWorkerSettings{
+			Concurrency:     cfg.Worker.Concurrency,
+			Prefetch:        cfg.Worker.Prefetch,
+			ShutdownTimeout: cfg.Worker.ShutdownTimeout,
+		})
+		if err != nil {
+			appLogger.Error("Failed to run image workers", "error", err)
+		}
+	}()
+
+	// Start and end scheduled prices, dropping the cached copies they change
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		productService.RunPriceScheduler(ctx, cfg.Pricing.ScheduleInterval, func(productID uint) {
+			if _, err := redisCache.Delete(context.Background(), handlers.ProductCacheKey(productID)); err != nil {
+				appLogger.Warn("Failed to evict cached product", "productID", productID, "error", err)
+			}
+		})
+	}()
+
+	select {
+	case err := <-serverErr:
 		appLogger.Fatal("Server failed to start", "error", err)
+	case <-ctx.Done():
 	}
+
+	// Stop accepting connections and wait for in-flight requests
+	appLogger.Info("Shutting down server", "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		appLogger.Error("Server shutdown did not complete", "error", err)
+	}
+
+	<-expiryDone
+	<-schedulerDone
+	<-relayDone
+	<-workersDone
+
+	// Close dependencies only once no handler can use them
+	taskQueue.Close()
+
+	if err := redisCache.Close(); err != nil {
+		appLogger.Error("Failed to close Redis client", "error", err)
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			appLogger.Error("Failed to close database", "error", err)
+		}
+	}
+
+	appLogger.Info("Server stopped")
 }
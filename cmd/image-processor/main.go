@@ -2,24 +2,21 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"os/signal"
+	"syscall"
 
 	// "log"
 	// "time"
 
 	"product-management-system/internal/config"
-	"product-management-system/internal/models"
+	"product-management-system/internal/queue"
 	"product-management-system/internal/repository"
 	"product-management-system/internal/service"
 	"product-management-system/pkg/logger"
+	"product-management-system/pkg/utils"
 
 	// "product-management-system/pkg/utils"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/streadway/amqp"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -32,12 +29,7 @@ func main() {
 	appLogger := logger.NewLogger()
 
 	// Database connection
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.Database.Host, cfg.Database.Port,
-		cfg.Database.User, cfg.Database.Password,
-		cfg.Database.DBName)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseDSN()), &gorm.Config{})
 	if err != nil {
 		appLogger.Fatal("Failed to connect to database", "error", err)
 	}
@@ -45,100 +37,70 @@ func main() {
 	// Initialize repositories
 	productRepo := repository.NewProductRepository(db)
 
-	// Initialize AWS Session
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(cfg.AWS.Region),
+	// Initialize blob store
+	blobStore, err := service.NewBlobStore(service.BlobStoreConfig{
+		Backend:        cfg.Storage.Backend,
+		Bucket:         cfg.AWS.S3Bucket,
+		Region:         cfg.AWS.Region,
+		Endpoint:       cfg.AWS.Endpoint,
+		ForcePathStyle: cfg.AWS.S3ForcePathStyle,
+		LocalDir:       cfg.Storage.Local.Dir,
+		PublicBaseURL:  cfg.Storage.PublicBaseURL,
 	})
 	if err != nil {
-		appLogger.Fatal("Failed to initialize AWS session", "error", err)
+		appLogger.Fatal("Failed to initialize blob store", "error", err)
 	}
 
-	// Initialize S3 Client
-	s3Client := s3.New(sess)
-
 	// Initialize image processor
+	fetcher, err := utils.NewFetcher(cfg.Fetch)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize image fetcher", "error", err)
+	}
+
 	imageProcessor := service.NewImageProcessor(
-		s3Client,
-		cfg.AWS.S3Bucket,
+		blobStore,
+		fetcher,
+		cfg.Images.Variants,
+		cfg.Worker.MaxImagesPerTask,
 		appLogger,
 	)
 
-	// Setup RabbitMQ connection
-	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%d", cfg.RabbitMQ.Host, cfg.RabbitMQ.Port))
-	if err != nil {
-		appLogger.Fatal("Failed to connect to RabbitMQ", "error", err)
+	// The in-memory queue only works inside the API
+	if cfg.Queue.Backend == queue.BackendMemory {
+		appLogger.Fatal("The memory queue backend runs image workers inside the API; use rabbitmq or postgres")
 	}
-	defer conn.Close()
-
-	ch, err := conn.Channel()
+	taskQueue, err := queue.New(cfg.QueueConfig(db), appLogger)
 	if err != nil {
-		appLogger.Fatal("Failed to open a channel", "error", err)
-	}
-	defer ch.Close()
-
-	// Declare queue
-	q, err := ch.QueueDeclare(
-		"image_processing_queue", // name
-		true,                     // durable
-		false,                    // delete when unused
-		false,                    // exclusive
-		false,                    // no-wait
-		nil,                      // arguments
-	)
-	if err != nil {
-		appLogger.Fatal("Failed to declare a queue", "error", err)
+		appLogger.Fatal("Failed to initialize task queue", "error", err)
 	}
 
-	// Consume messages
-	msgs, err := ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		false,  // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
+	appLogger.Info("Image Processing Service started. Waiting for messages...",
+		"queue", cfg.Queue.Backend,
+		"workers", cfg.Worker.Concurrency,
+		"prefetch", cfg.Worker.Prefetch,
+		"maxImagesPerTask", cfg.Worker.MaxImagesPerTask,
 	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	worker := service.NewImageWorker(productRepo, imageProcessor, appLogger)
+	err = service.RunImageWorkers(ctx, taskQueue, worker, service.ImageWorkerSettings{
+		Concurrency:     cfg.Worker.Concurrency,
+		Prefetch:        cfg.Worker.Prefetch,
+		ShutdownTimeout: cfg.Worker.ShutdownTimeout,
+	})
 	if err != nil {
-		appLogger.Fatal("Failed to register a consumer", "error", err)
+		appLogger.Error("Failed to consume tasks", "error", err)
 	}
 
-	// Process incoming messages
-	forever := make(chan bool)
-
-	go func() {
-		for d := range msgs {
-			// Parse message to ImageProcessingTask
-			task := &models.ImageProcessingTask{}
-			if err := json.Unmarshal(d.Body, task); err != nil {
-				appLogger.Error("Failed to parse message", "error", err)
-				d.Nack(false, false) // Negative acknowledge
-				continue
-			}
-
-			// Process images
-			if err := imageProcessor.ProcessImages(task); err != nil {
-				appLogger.Error("Image processing failed", "error", err, "productID", task.ProductID)
-				d.Nack(false, true) // Requeue
-				continue
-			}
-
-			// Update product with processed images
-			if err := productRepo.UpdateProductImages(
-				context.Background(),
-				task.ProductID,
-				task.CompressedImageURLs,
-			); err != nil {
-				appLogger.Error("Failed to update product images", "error", err)
-				d.Nack(false, true) // Requeue
-				continue
-			}
-
-			// Acknowledge message
-			d.Ack(false)
+	taskQueue.Close()
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			appLogger.Error("Failed to close database", "error", err)
 		}
-	}()
+	}
 
-	appLogger.Info("Image Processing Service started. Waiting for messages...")
-	<-forever
+	appLogger.Info("Image processor stopped")
 }
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"product-management-system/internal/config"
+	"product-management-system/internal/queue"
+	"product-management-system/pkg/logger"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// connectTimeout bounds how long commands wait for RabbitMQ
+const connectTimeout = 30 * time.Second
+
+const usage = `Usage: pmsctl <command> [flags]
+
+Commands:
+  dlq list     List dead-lettered image processing tasks
+  dlq replay   Move selected tasks back to the work queue
+  dlq purge    Permanently delete selected tasks
+
+Run "pmsctl dlq <command> -h" for command flags.
+`
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "dlq" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[2] {
+	case "list":
+		err = listDeadLetters(os.Args[3:])
+	case "replay":
+		err = replayDeadLetters(os.Args[3:])
+	case "purge":
+		err = purgeDeadLetters(os.Args[3:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fail(err)
+	}
+}
+
+// connect opens the configured task queue. Dead letters of the memory
+// backend live inside the API; use the admin endpoints for those.
+func connect() queue.TaskQueue {
+	cfg := config.LoadConfig()
+
+	var db *gorm.DB
+	switch cfg.Queue.Backend {
+	case queue.BackendMemory:
+		fail(errors.New("the memory queue lives inside the API; use the /api/v1/admin/dlq endpoints"))
+	case queue.BackendPostgres:
+		var err error
+		if db, err = gorm.Open(postgres.Open(cfg.DatabaseDSN()), &gorm.Config{}); err != nil {
+			fail(err)
+		}
+	}
+
+	taskQueue, err := queue.New(cfg.QueueConfig(db), logger.NewLogger())
+	if err != nil {
+		fail(err)
+	}
+
+	if rabbitMQ, ok := taskQueue.(*queue.RabbitMQQueue); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		defer cancel()
+		if err := rabbitMQ.WaitConnected(ctx); err != nil {
+			fail(err)
+		}
+	}
+	return taskQueue
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
+
+func listDeadLetters(args []string) error {
+	fs := flag.NewFlagSet("dlq list", flag.ExitOnError)
+	limit := fs.Int("limit", 100, "maximum number of messages to show (0 for all)")
+	asJSON := fs.Bool("json", false, "print messages as JSON")
+	fs.Parse(args)
+
+	messageQueue := connect()
+	defer messageQueue.Close()
+
+	messages, err := messageQueue.ListDeadLetters(*limit)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(messages)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MESSAGE ID\tPRODUCT\tIMAGES\tATTEMPTS\tFAILED AT\tERROR")
+	for _, m := range messages {
+		product, images := "-", "-"
+		if m.Task != nil {
+			product = fmt.Sprint(m.Task.ProductID)
+			images = strings.Join(m.Task.ImageURLs, ",")
+		}
+		failedAt := "-"
+		if m.FailedAt != nil {
+			failedAt = m.FailedAt.Format(time.RFC3339)
+		}
+		errMsg := m.Error
+		if m.DecodeError != "" {
+			errMsg = "undecodable payload: " + m.DecodeError
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", m.MessageID, product, images, m.Attempts, failedAt, errMsg)
+	}
+	return w.Flush()
+}
+
+func replayDeadLetters(args []string) error {
+	selector, err := parseSelector("dlq replay", args)
+	if err != nil {
+		return err
+	}
+
+	messageQueue := connect()
+	defer messageQueue.Close()
+
+	replayed, err := messageQueue.ReplayDeadLetters(selector)
+	fmt.Printf("Replayed %d message(s)\n", replayed)
+	return err
+}
+
+func purgeDeadLetters(args []string) error {
+	selector, err := parseSelector("dlq purge", args)
+	if err != nil {
+		return err
+	}
+
+	messageQueue := connect()
+	defer messageQueue.Close()
+
+	purged, err := messageQueue.PurgeDeadLetters(selector)
+	fmt.Printf("Purged %d message(s)\n", purged)
+	return err
+}
+
+func parseSelector(name string, args []string) (queue.DeadLetterSelector, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	ids := fs.String("id", "", "comma-separated message IDs")
+	productID := fs.Uint("product", 0, "only messages for this product ID")
+	olderThan := fs.Duration("older-than", 0, "only messages that failed at least this long ago, e.g. 72h")
+	all := fs.Bool("all", false, "select every message")
+	fs.Parse(args)
+
+	selector := queue.DeadLetterSelector{
+		ProductID: *productID,
+		OlderThan: *olderThan,
+		All:       *all,
+	}
+	if *ids != "" {
+		selector.MessageIDs = strings.Split(*ids, ",")
+	}
+	if *olderThan < 0 {
+		return selector, fmt.Errorf("-older-than must not be negative")
+	}
+	return selector, nil
+}
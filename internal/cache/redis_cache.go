@@ -43,6 +43,33 @@ func (c *RedisCache) Get(ctx context.Context, key string) (interface{}, error) {
 	return data, err
 }
 
-func (c *RedisCache) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+// GetInto decodes the cached JSON value into dest
+func (c *RedisCache) GetInto(ctx context.Context, key string, dest interface{}) error {
+	result, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(result, dest)
+}
+
+// Delete removes key and reports whether it existed
+func (c *RedisCache) Delete(ctx context.Context, key string) (bool, error) {
+	n, err := c.client.Del(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+func (c *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := c.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
 }
@@ -1,9 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"product-management-system/internal/models"
+	"product-management-system/internal/queue"
+	"product-management-system/pkg/money"
+	"product-management-system/pkg/utils"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
 type Config struct {
@@ -13,6 +21,8 @@ type Config struct {
 		User     string
 		Password string
 		DBName   string
+		// SSLMode is the libpq sslmode used by every command
+		SSLMode string
 	}
 	Redis struct {
 		Host     string
@@ -21,16 +31,81 @@ type Config struct {
 		Password string
 	}
 	RabbitMQ struct {
-		Host string
-		Port int
+		Host           string
+		Port           int
+		MaxAttempts    int
+		RetryBaseDelay time.Duration
+		RetryMaxDelay  time.Duration
+		// Reconnection backoff and how long publishes wait for the
+		// broker to confirm them
+		ReconnectBaseDelay time.Duration
+		ReconnectMaxDelay  time.Duration
+		ConfirmTimeout     time.Duration
+	}
+	Queue struct {
+		Backend  string
+		Postgres struct {
+			PollInterval time.Duration
+			Lease        time.Duration
+		}
+	}
+	Worker struct {
+		Concurrency      int
+		Prefetch         int
+		MaxImagesPerTask int
+		ShutdownTimeout  time.Duration
 	}
 	Server struct {
-		Host string
-		Port int
+		Host            string
+		Port            int
+		ShutdownTimeout time.Duration
 	}
 	AWS struct {
-		S3Bucket string
-		Region   string
+		S3Bucket         string
+		Region           string
+		Endpoint         string
+		S3ForcePathStyle bool
+	}
+	Images struct {
+		Variants []models.ImageVariantSpec
+		Uploads  struct {
+			MaxBytes      int64
+			MaxWidth      int
+			MaxHeight     int
+			PresignExpiry time.Duration
+		}
+	}
+	Fetch   utils.FetcherConfig
+	Storage struct {
+		Backend       string
+		PublicBaseURL string
+		Local         struct {
+			Dir string
+		}
+	}
+	Inventory struct {
+		ReservationTTL time.Duration
+		ExpiryInterval time.Duration
+	}
+	Pricing struct {
+		ScheduleInterval time.Duration
+	}
+	Outbox struct {
+		RelayInterval time.Duration
+		BatchSize     int
+		Lease         time.Duration
+		MaxBackoff    time.Duration
+		Retention     time.Duration
+	}
+	Currency struct {
+		Default   string
+		RatesFile string
+		Rounding  string
+	}
+	JWT struct {
+		Secret          string
+		AccessTokenTTL  time.Duration
+		RefreshTokenTTL time.Duration
 	}
 }
 
@@ -40,6 +115,43 @@ func LoadConfig() *Config {
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("./config")
 
+	viper.SetDefault("database.sslmode", "require")
+	viper.SetDefault("rabbitmq.maxattempts", 5)
+	viper.SetDefault("rabbitmq.retrybasedelay", "10s")
+	viper.SetDefault("rabbitmq.retrymaxdelay", "10m")
+	viper.SetDefault("rabbitmq.reconnectbasedelay", "1s")
+	viper.SetDefault("rabbitmq.reconnectmaxdelay", "30s")
+	viper.SetDefault("rabbitmq.confirmtimeout", "5s")
+	viper.SetDefault("queue.backend", "rabbitmq")
+	viper.SetDefault("queue.postgres.pollinterval", "1s")
+	viper.SetDefault("queue.postgres.lease", "10m")
+	viper.SetDefault("worker.concurrency", 4)
+	viper.SetDefault("worker.prefetch", 4)
+	viper.SetDefault("worker.maximagespertask", 4)
+	viper.SetDefault("worker.shutdowntimeout", "60s")
+	viper.SetDefault("server.shutdowntimeout", "30s")
+	viper.SetDefault("images.uploads.maxbytes", 20<<20)
+	viper.SetDefault("images.uploads.maxwidth", 8000)
+	viper.SetDefault("images.uploads.maxheight", 8000)
+	viper.SetDefault("images.uploads.presignexpiry", "15m")
+	viper.SetDefault("fetch.connecttimeout", "5s")
+	viper.SetDefault("fetch.readtimeout", "30s")
+	viper.SetDefault("fetch.maxbytes", 25<<20)
+	viper.SetDefault("fetch.maxredirects", 3)
+	viper.SetDefault("fetch.maxpixels", 50_000_000)
+	viper.SetDefault("inventory.reservationttl", "15m")
+	viper.SetDefault("inventory.expiryinterval", "1m")
+	viper.SetDefault("pricing.scheduleinterval", "1m")
+	viper.SetDefault("outbox.relayinterval", "1s")
+	viper.SetDefault("outbox.batchsize", 100)
+	viper.SetDefault("outbox.lease", "1m")
+	viper.SetDefault("outbox.maxbackoff", "5m")
+	viper.SetDefault("outbox.retention", "168h")
+	viper.SetDefault("currency.default", "USD")
+	viper.SetDefault("currency.rounding", string(money.RoundHalfEven))
+	viper.SetDefault("jwt.accesstokenttl", "15m")
+	viper.SetDefault("jwt.refreshtokenttl", "168h")
+
 	var config Config
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -50,5 +162,147 @@ func LoadConfig() *Config {
 		log.Fatalf("Unable to decode config into struct: %v", err)
 	}
 
+	if config.RabbitMQ.MaxAttempts < 1 {
+		log.Fatalf("rabbitmq.maxattempts must be at least 1")
+	}
+
+	rabbit := config.RabbitMQ
+	if rabbit.ReconnectBaseDelay <= 0 || rabbit.ReconnectMaxDelay <= 0 || rabbit.ConfirmTimeout <= 0 {
+		log.Fatalf("rabbitmq.reconnectbasedelay, reconnectmaxdelay and confirmtimeout must be positive")
+	}
+
+	if config.Queue.Postgres.PollInterval <= 0 || config.Queue.Postgres.Lease <= 0 {
+		log.Fatalf("queue.postgres.pollinterval and queue.postgres.lease must be positive")
+	}
+
+	if config.Worker.Concurrency < 1 || config.Worker.Prefetch < 1 || config.Worker.MaxImagesPerTask < 1 {
+		log.Fatalf("worker.concurrency, worker.prefetch and worker.maximagespertask must be at least 1")
+	}
+
+	if len(config.Images.Variants) == 0 {
+		config.Images.Variants = defaultImageVariants
+	}
+	if err := validateImageVariants(config.Images.Variants); err != nil {
+		log.Fatalf("Invalid images.variants: %v", err)
+	}
+
+	uploads := config.Images.Uploads
+	if uploads.MaxBytes < 1 || uploads.MaxWidth < 1 || uploads.MaxHeight < 1 || uploads.PresignExpiry <= 0 {
+		log.Fatalf("images.uploads.maxbytes, maxwidth, maxheight and presignexpiry must be positive")
+	}
+
+	fetch := config.Fetch
+	if fetch.ConnectTimeout <= 0 || fetch.ReadTimeout <= 0 || fetch.MaxBytes < 1 || fetch.MaxPixels < 1 || fetch.MaxRedirects < 0 {
+		log.Fatalf("fetch.connecttimeout, readtimeout, maxbytes and maxpixels must be positive and fetch.maxredirects not negative")
+	}
+
+	if config.Inventory.ReservationTTL <= 0 || config.Inventory.ExpiryInterval <= 0 {
+		log.Fatalf("inventory.reservationttl and inventory.expiryinterval must be positive")
+	}
+
+	if config.Pricing.ScheduleInterval <= 0 {
+		log.Fatalf("pricing.scheduleinterval must be positive")
+	}
+
+	outbox := config.Outbox
+	if outbox.RelayInterval <= 0 || outbox.BatchSize < 1 || outbox.Lease <= 0 || outbox.MaxBackoff <= 0 || outbox.Retention <= 0 {
+		log.Fatalf("outbox.relayinterval, batchsize, lease, maxbackoff and retention must be positive")
+	}
+
+	currency, err := money.NormalizeCurrency(config.Currency.Default)
+	if err != nil {
+		log.Fatalf("Invalid currency.default: %v", err)
+	}
+	config.Currency.Default = currency
+	if _, err := money.ParseRounding(config.Currency.Rounding); err != nil {
+		log.Fatalf("Invalid currency.rounding: %v", err)
+	}
+
+	if config.JWT.Secret == "" {
+		log.Fatalf("jwt.secret must be set")
+	}
+
 	return &config
 }
+
+// DatabaseDSN returns the PostgreSQL connection string
+func (c *Config) DatabaseDSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Database.Host, c.Database.Port,
+		c.Database.User, c.Database.Password,
+		c.Database.DBName, c.Database.SSLMode)
+}
+
+// QueueConfig returns the task queue settings. db is only used by the
+// postgres backend.
+func (c *Config) QueueConfig(db *gorm.DB) queue.Config {
+	return queue.Config{
+		Backend: c.Queue.Backend,
+		RetryPolicy: queue.RetryPolicy{
+			MaxAttempts: c.RabbitMQ.MaxAttempts,
+			BaseDelay:   c.RabbitMQ.RetryBaseDelay,
+			MaxDelay:    c.RabbitMQ.RetryMaxDelay,
+		},
+		Host: c.RabbitMQ.Host,
+		Port: c.RabbitMQ.Port,
+		ConnectionPolicy: queue.ConnectionPolicy{
+			ReconnectBaseDelay: c.RabbitMQ.ReconnectBaseDelay,
+			ReconnectMaxDelay:  c.RabbitMQ.ReconnectMaxDelay,
+			ConfirmTimeout:     c.RabbitMQ.ConfirmTimeout,
+		},
+		DB:           db,
+		PollInterval: c.Queue.Postgres.PollInterval,
+		Lease:        c.Queue.Postgres.Lease,
+	}
+}
+
+// defaultImageVariants is used when images.variants isn't configured
+var defaultImageVariants = []models.ImageVariantSpec{
+	{Name: "thumbnail", Width: 150, Height: 150, Fit: utils.FitCover, Quality: 70},
+	{Name: "medium", Width: 800, Fit: utils.FitContain, Quality: 75},
+	{Name: "large", Width: 1600, Height: 1600, Fit: utils.FitContain, Quality: 80},
+	{Name: "original", Quality: 90},
+}
+
+func validateImageVariants(variants []models.ImageVariantSpec) error {
+	seen := make(map[string]bool)
+	for i := range variants {
+		v := &variants[i]
+		if v.Name == "" || strings.ContainsAny(v.Name, "/ ") {
+			return fmt.Errorf("variant %d needs a name without slashes or spaces", i)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variant %q", v.Name)
+		}
+		seen[v.Name] = true
+
+		switch v.Fit {
+		case "":
+			v.Fit = utils.FitContain
+		case utils.FitContain, utils.FitCover:
+		default:
+			return fmt.Errorf("variant %q: unknown fit %q", v.Name, v.Fit)
+		}
+
+		switch v.Format {
+		case "", utils.FormatJPEG, utils.FormatPNG, utils.FormatWebP:
+		case "avif":
+			return fmt.Errorf("variant %q: avif output needs a native encoder and is not supported; use webp", v.Name)
+		default:
+			return fmt.Errorf("variant %q: unsupported format %q", v.Name, v.Format)
+		}
+
+		if v.Format == utils.FormatWebP && (v.Width > utils.MaxWebPDimension || v.Height > utils.MaxWebPDimension) {
+			return fmt.Errorf("variant %q: webp images can't be larger than %dpx", v.Name, utils.MaxWebPDimension)
+		}
+
+		// Quality only affects JPEG; PNG and WebP are lossless
+		if v.Quality == 0 {
+			v.Quality = 80
+		}
+		if v.Quality < 1 || v.Quality > 100 {
+			return fmt.Errorf("variant %q: quality must be between 1 and 100", v.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"product-management-system/internal/queue"
+	"product-management-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	deadLetters queue.DeadLetters
+	logger      *logger.Logger
+}
+
+func NewAdminHandler(
+	deadLetters queue.DeadLetters,
+	logger *logger.Logger,
+) *AdminHandler {
+	return &AdminHandler{
+		deadLetters: deadLetters,
+		logger:      logger,
+	}
+}
+
+// deadLetterSelectorRequest is the body of the replay and purge endpoints.
+// OlderThan is a Go duration string such as "72h".
+type deadLetterSelectorRequest struct {
+	MessageIDs []string `json:"message_ids"`
+	ProductID  uint     `json:"product_id"`
+	OlderThan  string   `json:"older_than"`
+	All        bool     `json:"all"`
+}
+
+func (r *deadLetterSelectorRequest) toSelector() (queue.DeadLetterSelector, error) {
+	selector := queue.DeadLetterSelector{
+		MessageIDs: r.MessageIDs,
+		ProductID:  r.ProductID,
+		All:        r.All,
+	}
+	if r.OlderThan != "" {
+		olderThan, err := time.ParseDuration(r.OlderThan)
+		if err != nil || olderThan < 0 {
+			return selector, errors.New("older_than must be a non-negative duration such as 72h")
+		}
+		selector.OlderThan = olderThan
+	}
+	return selector, nil
+}
+
+func (h *AdminHandler) ListDeadLetters(c *gin.Context) {
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	messages, err := h.deadLetters.ListDeadLetters(limit)
+	if err != nil {
+		h.logger.Error("Failed to list dead-lettered messages", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": messages})
+}
+
+func (h *AdminHandler) ReplayDeadLetters(c *gin.Context) {
+	selector, ok := h.bindSelector(c)
+	if !ok {
+		return
+	}
+
+	replayed, err := h.deadLetters.ReplayDeadLetters(selector)
+	if err != nil {
+		c.JSON(deadLetterErrorStatus(err), gin.H{"error": err.Error(), "replayed": replayed})
+		return
+	}
+
+	h.logger.Info("Replayed dead-lettered messages", "count", replayed, "admin", currentClaims(c).Username)
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+func (h *AdminHandler) PurgeDeadLetters(c *gin.Context) {
+	selector, ok := h.bindSelector(c)
+	if !ok {
+		return
+	}
+
+	purged, err := h.deadLetters.PurgeDeadLetters(selector)
+	if err != nil {
+		c.JSON(deadLetterErrorStatus(err), gin.H{"error": err.Error(), "purged": purged})
+		return
+	}
+
+	h.logger.Info("Purged dead-lettered messages", "count", purged, "admin", currentClaims(c).Username)
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+func (h *AdminHandler) bindSelector(c *gin.Context) (queue.DeadLetterSelector, bool) {
+	var req deadLetterSelectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return queue.DeadLetterSelector{}, false
+	}
+
+	selector, err := req.toSelector()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return selector, false
+	}
+	return selector, true
+}
+
+func deadLetterErrorStatus(err error) int {
+	if errors.Is(err, queue.ErrEmptySelector) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"product-management-system/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const claimsContextKey = "authClaims"
+
+// RequireAuth rejects requests without a valid bearer access token and
+// stores the token claims in both the gin and the request context
+func RequireAuth(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing bearer token"})
+			return
+		}
+
+		authenticate(c, authService, token)
+	}
+}
+
+// OptionalAuth lets anonymous requests through but still authenticates
+// callers that send a bearer token, so visibility rules can see who they are
+func OptionalAuth(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		authenticate(c, authService, token)
+	}
+}
+
+// RequireRole rejects authenticated callers without the given role. It
+// must run after RequireAuth.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := currentClaims(c)
+		if claims == nil || claims.Role != role {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func authenticate(c *gin.Context, authService *service.AuthService, token string) {
+	claims, err := authService.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		c.AbortWithStatusJSON(authErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Set(claimsContextKey, claims)
+	c.Request = c.Request.WithContext(service.ContextWithClaims(c.Request.Context(), claims))
+	c.Next()
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+// currentClaims returns the claims stored by RequireAuth, or nil
+func currentClaims(c *gin.Context) *service.TokenClaims {
+	if value, ok := c.Get(claimsContextKey); ok {
+		if claims, ok := value.(*service.TokenClaims); ok {
+			return claims
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"product-management-system/internal/service"
+	"product-management-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+	logger          *logger.Logger
+}
+
+func NewCategoryHandler(
+	categoryService *service.CategoryService,
+	logger *logger.Logger,
+) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+		logger:          logger,
+	}
+}
+
+// categoryRequest is the body of CreateCategory and UpdateCategory. An
+// empty slug is derived from the name; a null parent_id makes a root.
+type categoryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+func (r *categoryRequest) toCategory() *models.Category {
+	return &models.Category{Name: r.Name, Slug: r.Slug, ParentID: r.ParentID}
+}
+
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req categoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := req.toCategory()
+	if err := h.categoryService.CreateCategory(c.Request.Context(), category); err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	category, err := h.categoryService.FindCategoryByID(c.Request.Context(), uint(categoryID))
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// ListCategories returns the whole tree, or the subtree under root_id, as
+// a flat list with parents before their children
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	var rootID *uint
+	if raw := c.Query("root_id"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid root_id"})
+			return
+		}
+		id := uint(parsed)
+		rootID = &id
+	}
+
+	categories, err := h.categoryService.ListCategories(c.Request.Context(), rootID)
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if categories == nil {
+		categories = []models.Category{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": categories})
+}
+
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req categoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.categoryService.UpdateCategory(c.Request.Context(), uint(categoryID), req.toCategory())
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(c.Request.Context(), uint(categoryID)); err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// categoryErrorStatus maps category errors to HTTP status codes
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrCategoryExists),
+		errors.Is(err, repository.ErrCategoryInUse):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrParentNotFound),
+		errors.Is(err, repository.ErrCategoryCycle),
+		errors.Is(err, service.ErrCategoryNameRequired),
+		errors.Is(err, service.ErrInvalidSlug):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
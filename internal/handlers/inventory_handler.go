@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"product-management-system/internal/cache"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"product-management-system/internal/service"
+	"product-management-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryHandler struct {
+	inventoryService *service.InventoryService
+	redisCache       *cache.RedisCache
+	logger           *logger.Logger
+}
+
+func NewInventoryHandler(
+	inventoryService *service.InventoryService,
+	redisCache *cache.RedisCache,
+	logger *logger.Logger,
+) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryService: inventoryService,
+		redisCache:       redisCache,
+		logger:           logger,
+	}
+}
+
+// reserveRequest is the body of Reserve. variant_id is required for
+// products with variants and must be omitted otherwise.
+type reserveRequest struct {
+	ProductID uint  `json:"product_id" binding:"required"`
+	VariantID *uint `json:"variant_id"`
+	Quantity  int   `json:"quantity" binding:"required,min=1"`
+}
+
+func (h *InventoryHandler) Reserve(c *gin.Context) {
+	var req reserveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservation, err := h.inventoryService.Reserve(c.Request.Context(), req.ProductID, req.VariantID, req.Quantity)
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, reservation)
+	c.JSON(http.StatusCreated, reservation)
+}
+
+func (h *InventoryHandler) GetReservation(c *gin.Context) {
+	reservationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation ID"})
+		return
+	}
+
+	reservation, err := h.inventoryService.FindReservation(c.Request.Context(), uint(reservationID))
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (h *InventoryHandler) CommitReservation(c *gin.Context) {
+	reservationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation ID"})
+		return
+	}
+
+	reservation, err := h.inventoryService.CommitReservation(c.Request.Context(), uint(reservationID))
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, reservation)
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (h *InventoryHandler) ReleaseReservation(c *gin.Context) {
+	reservationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation ID"})
+		return
+	}
+
+	reservation, err := h.inventoryService.ReleaseReservation(c.Request.Context(), uint(reservationID))
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, reservation)
+	c.JSON(http.StatusOK, reservation)
+}
+
+// evictProduct drops the cached product, whose stock figures changed
+func (h *InventoryHandler) evictProduct(c *gin.Context, reservation *models.StockReservation) {
+	if _, err := h.redisCache.Delete(c.Request.Context(), ProductCacheKey(reservation.ProductID)); err != nil {
+		h.logger.Warn("Failed to evict cached product", "productID", reservation.ProductID, "error", err)
+	}
+}
+
+// inventoryErrorStatus maps inventory errors to HTTP status codes
+func inventoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrReservationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrInsufficientStock),
+		errors.Is(err, repository.ErrReservationNotActive):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrReservationExpired):
+		return http.StatusGone
+	case errors.Is(err, repository.ErrVariantRequired),
+		errors.Is(err, service.ErrInvalidQuantity):
+		return http.StatusBadRequest
+	default:
+		return productErrorStatus(err)
+	}
+}
@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"product-management-system/internal/cache"
 	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
 	"product-management-system/internal/service"
 	"product-management-system/pkg/logger"
+	"product-management-system/pkg/money"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,8 +44,11 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	// The owner always comes from the access token, never the request body
+	product.UserID = currentClaims(c).UserID()
+
 	if err := h.productService.CreateProduct(c.Request.Context(), &product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -56,9 +63,14 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	}
 
 	// Try cache first
-	cacheKey := fmt.Sprintf("product:%d", productID)
-	cachedProduct, err := h.redisCache.Get(c.Request.Context(), cacheKey)
-	if err == nil {
+	cacheKey := ProductCacheKey(uint(productID))
+	var cachedProduct models.Product
+	if err := h.redisCache.GetInto(c.Request.Context(), cacheKey, &cachedProduct); err == nil {
+		// Cached entries are shared between callers, so re-check visibility
+		if err := h.productService.AuthorizeRead(c.Request.Context(), &cachedProduct); err != nil {
+			c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusOK, cachedProduct)
 		return
 	}
@@ -66,36 +78,316 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	// Fetch from database
 	product, err := h.productService.FindProductByID(c.Request.Context(), uint(productID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	// Cache the result
-	if err := h.redisCache.Set(c.Request.Context(), cacheKey, product, time.Hour); err != nil {
-		h.logger.Warn("Failed to cache product", "error", err)
+	// Cache the result once the worker is done with it, since the worker
+	// updates the product without evicting the cache
+	if !imagesInFlight(product) {
+		if err := h.redisCache.Set(c.Request.Context(), cacheKey, product, time.Hour); err != nil {
+			h.logger.Warn("Failed to cache product", "error", err)
+		}
 	}
 
 	c.JSON(http.StatusOK, product)
 }
 
+// imageStatusResponse reports the image processing state of a product
+type imageStatusResponse struct {
+	ProductID       uint                    `json:"product_id"`
+	Status          string                  `json:"status"`
+	Error           string                  `json:"error,omitempty"`
+	Images          []string                `json:"images"`
+	ProcessedImages []models.ProcessedImage `json:"processed_images"`
+	ProcessedAt     *time.Time              `json:"processed_at"`
+}
+
+// GetImageStatus always reads from the database so clients can poll it
+func (h *ProductHandler) GetImageStatus(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, err := h.productService.FindProductByID(c.Request.Context(), uint(productID))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, imageStatusResponse{
+		ProductID:       product.ID,
+		Status:          product.ImageStatus,
+		Error:           product.ImageError,
+		Images:          product.ProductImages,
+		ProcessedImages: product.ProcessedImages,
+		ProcessedAt:     product.ProcessedAt,
+	})
+}
+
+// listProductsQuery holds the validated query parameters of ListProducts
+type listProductsQuery struct {
+	UserID      uint     `form:"user_id" binding:"required"`
+	MinPrice    *float64 `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice    *float64 `form:"max_price" binding:"omitempty,gte=0"`
+	ProductName string   `form:"product_name"`
+	Currency    string   `form:"currency"`
+	Rounding    string   `form:"rounding" binding:"omitempty,oneof=half_even half_up down up"`
+	CategoryID  *uint    `form:"category_id"`
+	Tags        []string `form:"tag"`
+	Attributes  []string `form:"attr"`
+	Sort        string   `form:"sort" binding:"omitempty,oneof=created_at price name"`
+	Order       string   `form:"order" binding:"omitempty,oneof=asc desc"`
+	Limit       int      `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset      int      `form:"offset" binding:"omitempty,min=0"`
+	Cursor      string   `form:"cursor"`
+}
+
+// productListResponse is the envelope returned by ListProducts
+type productListResponse struct {
+	Items      []models.Product `json:"items"`
+	Total      int64            `json:"total"`
+	Limit      int              `json:"limit"`
+	Offset     int              `json:"offset"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
 func (h *ProductHandler) ListProducts(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Query("user_id"), 10, 64)
+	var query listProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := query.toFilter()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	filters := map[string]interface{}{
-		"min_price":    c.Query("min_price"),
-		"max_price":    c.Query("max_price"),
-		"product_name": c.Query("product_name"),
+	page, err := h.productService.ListProductsByUser(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	items := page.Products
+	if items == nil {
+		items = []models.Product{}
+	}
+	if filter.Currency != "" {
+		err := h.productService.ConvertPrices(c.Request.Context(), items, filter.Currency, money.Rounding(query.Rounding))
+		if err != nil {
+			c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, productListResponse{
+		Items:      items,
+		Total:      page.Total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// toFilter checks cross-field constraints and builds the repository filter
+func (q *listProductsQuery) toFilter() (repository.ProductFilter, error) {
+	filter := repository.ProductFilter{
+		UserID:      q.UserID,
+		Currency:    strings.ToUpper(strings.TrimSpace(q.Currency)),
+		MinPrice:    q.MinPrice,
+		MaxPrice:    q.MaxPrice,
+		ProductName: strings.TrimSpace(q.ProductName),
+		Sort:        q.Sort,
+		Descending:  q.Order != "asc",
+		Limit:       q.Limit,
+		Offset:      q.Offset,
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = repository.SortCreatedAt
+	}
+	if filter.Limit == 0 {
+		filter.Limit = repository.DefaultListLimit
+	}
+
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		return filter, errors.New("min_price must not exceed max_price")
 	}
 
-	products, err := h.productService.ListProductsByUser(c.Request.Context(), uint(userID), filters)
+	filter.CategoryID = q.CategoryID
+	for _, tag := range q.Tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+	for _, attr := range q.Attributes {
+		key, value, ok := strings.Cut(attr, ":")
+		if !ok || key == "" {
+			return filter, errors.New("attr must have the form key:value")
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = make(map[string]string)
+		}
+		filter.Attributes[key] = value
+	}
+
+	if q.Cursor != "" {
+		if q.Offset != 0 {
+			return filter, errors.New("cursor and offset cannot be combined")
+		}
+
+		cursor, err := repository.DecodeCursor(q.Cursor)
+		if err != nil {
+			return filter, err
+		}
+		if cursor.Sort != filter.Sort || cursor.Descending != filter.Descending {
+			return filter, errors.New("cursor does not match the requested sort order")
+		}
+		if cursor.Sort == repository.SortPrice && cursor.Currency != filter.Currency {
+			return filter, errors.New("cursor does not match the requested currency")
+		}
+		filter.Cursor = cursor
+	}
+
+	return filter, nil
+}
+
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	var input models.Product
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.logger.Error("Invalid product data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.UpdateProduct(c.Request.Context(), uint(productID), &input)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, product.ID)
+	c.JSON(http.StatusOK, product)
+}
+
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != "application/merge-patch+json" && contentType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/merge-patch+json"})
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.PatchProduct(c.Request.Context(), uint(productID), patch)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, product.ID)
+	c.JSON(http.StatusOK, product)
+}
+
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.productService.DeleteProduct(c.Request.Context(), uint(productID)); err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, uint(productID))
+	c.Status(http.StatusNoContent)
+}
+
+// evictProduct removes the cached copy written by GetProductByID
+func (h *ProductHandler) evictProduct(c *gin.Context, productID uint) {
+	if _, err := h.redisCache.Delete(c.Request.Context(), ProductCacheKey(productID)); err != nil {
+		h.logger.Warn("Failed to evict cached product", "productID", productID, "error", err)
+	}
+}
+
+func imagesInFlight(product *models.Product) bool {
+	return product.ImageStatus == models.ImageStatusPending || product.ImageStatus == models.ImageStatusProcessing
+}
+
+// ProductCacheKey is the cache key of a product fetched by ID
+func ProductCacheKey(productID uint) string {
+	return fmt.Sprintf("product:%d", productID)
+}
+
+// productErrorStatus maps service errors to HTTP status codes
+func productErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound),
+		errors.Is(err, repository.ErrVariantNotFound),
+		errors.Is(err, repository.ErrPriceScheduleNotFound),
+		errors.Is(err, repository.ErrPriceListEntryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrSKUExists),
+		errors.Is(err, repository.ErrVariantOptionsExist),
+		errors.Is(err, repository.ErrStockBelowReserved),
+		errors.Is(err, repository.ErrVariantPricesSet),
+		errors.Is(err, repository.ErrPriceScheduleOverlap),
+		errors.Is(err, repository.ErrPriceScheduleFinished):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrUnsupportedImageType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrPresignNotSupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, service.ErrProductNameRequired),
+		errors.Is(err, service.ErrInvalidPatch),
+		errors.Is(err, service.ErrInvalidVisibility),
+		errors.Is(err, service.ErrImageDimensions),
+		errors.Is(err, service.ErrInvalidCategory),
+		errors.Is(err, service.ErrInvalidTags),
+		errors.Is(err, service.ErrInvalidAttribute),
+		errors.Is(err, service.ErrInvalidSKU),
+		errors.Is(err, service.ErrInvalidVariantOptions),
+		errors.Is(err, service.ErrInvalidVariantPrice),
+		errors.Is(err, service.ErrInvalidVariantImages),
+		errors.Is(err, service.ErrInvalidStock),
+		errors.Is(err, service.ErrInvalidPriceSchedule),
+		errors.Is(err, service.ErrInvalidPrice),
+		errors.Is(err, service.ErrUnsupportedCurrency),
+		errors.Is(err, money.ErrUnknownCurrency),
+		errors.Is(err, repository.ErrCurrencyMismatch),
+		errors.Is(err, repository.ErrEmptySearchQuery),
+		errors.Is(err, repository.ErrInvalidCursor):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
 }
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+
+	"github.com/gin-gonic/gin"
+)
+
+// schedulePriceRequest is the body of SchedulePrice. amount_minor is in minor
+// units and currency defaults to the product's. Without ends_at the price
+// change is permanent.
+type schedulePriceRequest struct {
+	Amount   *int64     `json:"amount_minor" binding:"required"`
+	Currency string     `json:"currency"`
+	StartsAt time.Time  `json:"starts_at" binding:"required"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// listPriceRequest is the body of SetListPrice, in minor units of the
+// currency in the path
+type listPriceRequest struct {
+	Amount *int64 `json:"amount_minor" binding:"required"`
+}
+
+// GetPrices returns a product's price history and scheduled prices
+func (h *ProductHandler) GetPrices(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	timeline, err := h.productService.PriceTimeline(c.Request.Context(), uint(productID))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if timeline.Changes == nil {
+		timeline.Changes = []models.PriceChange{}
+	}
+	if timeline.Schedules == nil {
+		timeline.Schedules = []models.PriceSchedule{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history":   timeline.Changes,
+		"schedules": timeline.Schedules,
+	})
+}
+
+func (h *ProductHandler) SchedulePrice(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req schedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule := &models.PriceSchedule{
+		Price:    money.Money{Amount: *req.Amount, Currency: req.Currency},
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+	}
+	if err := h.productService.SchedulePrice(c.Request.Context(), uint(productID), schedule); err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// CancelPriceSchedule cancels a pending schedule, or ends an active one
+// and restores the price from before it
+func (h *ProductHandler) CancelPriceSchedule(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	scheduleID, err := strconv.ParseUint(c.Param("schedule_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	schedule, err := h.productService.CancelPriceSchedule(c.Request.Context(), uint(productID), uint(scheduleID))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, uint(productID))
+	c.JSON(http.StatusOK, schedule)
+}
+
+// GetPriceList returns a product's prices in other currencies
+func (h *ProductHandler) GetPriceList(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	entries, err := h.productService.ListPriceList(c.Request.Context(), uint(productID))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []models.PriceListEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": entries})
+}
+
+// SetListPrice sets a product's price in the currency in the path
+func (h *ProductHandler) SetListPrice(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req listPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price := money.Money{Amount: *req.Amount, Currency: c.Param("currency")}
+	entry, err := h.productService.SetListPrice(c.Request.Context(), uint(productID), price)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *ProductHandler) DeleteListPrice(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.productService.DeleteListPrice(c.Request.Context(), uint(productID), c.Param("currency")); err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
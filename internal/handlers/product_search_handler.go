@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchProductsQuery holds the validated query parameters of SearchProducts
+type searchProductsQuery struct {
+	Query    string   `form:"q" binding:"required"`
+	MinPrice *float64 `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice *float64 `form:"max_price" binding:"omitempty,gte=0"`
+	Limit    int      `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset   int      `form:"offset" binding:"omitempty,min=0"`
+}
+
+// searchHitResponse is a product with its search rank and highlights.
+// Highlights are HTML-escaped with matches wrapped in <mark> tags.
+type searchHitResponse struct {
+	Product            models.Product `json:"product"`
+	Rank               float64        `json:"rank"`
+	NameHighlight      string         `json:"name_highlight"`
+	DescriptionSnippet string         `json:"description_snippet"`
+}
+
+// priceBucketResponse counts matches with min <= price < max; max is null
+// for the last bucket
+type priceBucketResponse struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
+
+type searchFacetsResponse struct {
+	Price []priceBucketResponse `json:"price"`
+}
+
+// searchResponse is the envelope returned by SearchProducts
+type searchResponse struct {
+	Items  []searchHitResponse  `json:"items"`
+	Total  int64                `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+	Facets searchFacetsResponse `json:"facets"`
+}
+
+// SearchProducts ranks visible products by how well their name and
+// description match q
+func (h *ProductHandler) SearchProducts(c *gin.Context) {
+	var query searchProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_price must not exceed max_price"})
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = repository.DefaultListLimit
+	}
+
+	result, err := h.productService.SearchProducts(c.Request.Context(), repository.SearchFilter{
+		Query:    query.Query,
+		MinPrice: query.MinPrice,
+		MaxPrice: query.MaxPrice,
+		Limit:    query.Limit,
+		Offset:   query.Offset,
+	})
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	response := searchResponse{
+		Items:  make([]searchHitResponse, 0, len(result.Hits)),
+		Total:  result.Total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+		Facets: searchFacetsResponse{Price: make([]priceBucketResponse, 0, len(result.PriceFacets))},
+	}
+	for _, hit := range result.Hits {
+		response.Items = append(response.Items, searchHitResponse{
+			Product:            hit.Product,
+			Rank:               hit.Rank,
+			NameHighlight:      hit.NameHighlight,
+			DescriptionSnippet: hit.DescriptionSnippet,
+		})
+	}
+	for _, bucket := range result.PriceFacets {
+		response.Facets.Price = append(response.Facets.Price, priceBucketResponse{
+			Min:   bucket.Min,
+			Max:   bucket.Max,
+			Count: bucket.Count,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead leaves room for multipart headers and boundaries on
+// top of the image size limit
+const multipartOverhead = 1 << 20
+
+// createUploadSlotRequest is the body of CreateUploadSlot
+type createUploadSlotRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+}
+
+// uploadSlotResponse tells the client where and how to upload an image
+type uploadSlotResponse struct {
+	UploadID  string            `json:"upload_id"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// UploadImage accepts a multipart upload with the image in the "image"
+// field and adds it to the product
+func (h *ProductHandler) UploadImage(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.productService.MaxUploadBytes()+multipartOverhead)
+	file, _, err := c.Request.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image upload is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"image\" is required"})
+		return
+	}
+	defer file.Close()
+
+	product, err := h.productService.UploadProductImage(c.Request.Context(), uint(productID), file)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, product.ID)
+	c.JSON(http.StatusAccepted, product)
+}
+
+// CreateUploadSlot returns a presigned URL for uploading an image straight
+// to storage. The client confirms the upload with ConfirmUpload.
+func (h *ProductHandler) CreateUploadSlot(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req createUploadSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slot, err := h.productService.CreateUploadSlot(c.Request.Context(), uint(productID), req.ContentType)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, uploadSlotResponse{
+		UploadID:  slot.UploadID,
+		URL:       slot.URL,
+		Method:    slot.Method,
+		Headers:   slot.Headers,
+		ExpiresAt: slot.ExpiresAt,
+	})
+}
+
+// ConfirmUpload validates an image uploaded to a slot and adds it to the
+// product
+func (h *ProductHandler) ConfirmUpload(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, err := h.productService.ConfirmUpload(c.Request.Context(), uint(productID), c.Param("upload_id"))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, product.ID)
+	c.JSON(http.StatusAccepted, product)
+}
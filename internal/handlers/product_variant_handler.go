@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"product-management-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// variantRequest is the body of CreateVariant and UpdateVariant.
+// price_amount is in minor units of the product's currency; null makes the
+// variant sell at the product's price.
+type variantRequest struct {
+	SKU         string            `json:"sku" binding:"required"`
+	Options     map[string]string `json:"options"`
+	PriceAmount *int64            `json:"price_amount"`
+	Images      []string          `json:"images"`
+	Stock       int               `json:"stock"`
+}
+
+func (r *variantRequest) toVariant() *models.ProductVariant {
+	return &models.ProductVariant{
+		SKU:         r.SKU,
+		Options:     r.Options,
+		PriceAmount: r.PriceAmount,
+		Images:      r.Images,
+		Stock:       r.Stock,
+	}
+}
+
+func (h *ProductHandler) ListVariants(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	variants, err := h.productService.ListVariants(c.Request.Context(), uint(productID))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if variants == nil {
+		variants = []models.ProductVariant{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": variants})
+}
+
+func (h *ProductHandler) GetVariant(c *gin.Context) {
+	productID, variantID, ok := variantParams(c)
+	if !ok {
+		return
+	}
+
+	variant, err := h.productService.FindVariant(c.Request.Context(), productID, variantID)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+func (h *ProductHandler) CreateVariant(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req variantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant := req.toVariant()
+	if err := h.productService.CreateVariant(c.Request.Context(), uint(productID), variant); err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// The product's cached price range is now stale
+	h.evictProduct(c, uint(productID))
+	c.JSON(http.StatusCreated, variant)
+}
+
+func (h *ProductHandler) UpdateVariant(c *gin.Context) {
+	productID, variantID, ok := variantParams(c)
+	if !ok {
+		return
+	}
+
+	var req variantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant, err := h.productService.UpdateVariant(c.Request.Context(), productID, variantID, req.toVariant())
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, productID)
+	c.JSON(http.StatusOK, variant)
+}
+
+func (h *ProductHandler) DeleteVariant(c *gin.Context) {
+	productID, variantID, ok := variantParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.DeleteVariant(c.Request.Context(), productID, variantID); err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.evictProduct(c, productID)
+	c.Status(http.StatusNoContent)
+}
+
+// variantParams parses the product and variant IDs from the path,
+// responding with 400 if either is invalid
+func variantParams(c *gin.Context) (productID, variantID uint, ok bool) {
+	parsedProduct, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return 0, 0, false
+	}
+	parsedVariant, err := strconv.ParseUint(c.Param("variant_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return 0, 0, false
+	}
+	return uint(parsedProduct), uint(parsedVariant), true
+}
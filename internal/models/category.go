@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// Category is a node in the product category tree. Path lists the IDs from
+// the root down to and including the category, e.g. "/1/4/9/", so a
+// subtree is every category whose path starts with its root's path.
+type Category struct {
+	gorm.Model
+	Name     string `gorm:"not null"`
+	Slug     string `gorm:"not null;uniqueIndex"`
+	ParentID *uint  `gorm:"index"`
+	Path     string `gorm:"not null;index"`
+}
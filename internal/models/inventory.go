@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stock reservation states. Only active reservations hold stock.
+const (
+	ReservationActive    = "active"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// StockReservation holds Quantity units of a product, or of one of its
+// variants, until it is committed, released or expires. Committing takes
+// the units out of stock; releasing or expiring returns them.
+type StockReservation struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index"`
+	ProductID uint      `gorm:"not null;index"`
+	VariantID *uint     `gorm:"index"`
+	Quantity  int       `gorm:"not null"`
+	Status    string    `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox topics say what an OutboxMessage's payload is and where it is
+// published
+const (
+	OutboxTopicImageProcessing = "image_processing"
+)
+
+// OutboxMessage is a message written in the same transaction as the change
+// it announces and published afterwards by the outbox relay, so the two
+// can't diverge. Unsent messages are invisible to the relay until
+// AvailableAt, which is pushed back while a relay is publishing them and
+// after failed attempts.
+type OutboxMessage struct {
+	ID          uint            `gorm:"primarykey"`
+	Topic       string          `gorm:"not null"`
+	Payload     json.RawMessage `gorm:"type:jsonb;not null"`
+	Attempts    int             `gorm:"not null;default:0"`
+	LastError   string
+	AvailableAt time.Time  `gorm:"not null;index:idx_outbox_pending,where:sent_at IS NULL"`
+	SentAt      *time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"product-management-system/pkg/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Price change sources recorded on PriceChange.Source
+const (
+	PriceSourceManual        = "manual"
+	PriceSourceScheduleStart = "schedule_start"
+	PriceSourceScheduleEnd   = "schedule_end"
+)
+
+// Price schedule states. Scheduled prices become active at StartsAt and
+// end at EndsAt; schedules without an end are a one-off change and end as
+// soon as they are applied.
+const (
+	PriceScheduleScheduled = "scheduled"
+	PriceScheduleActive    = "active"
+	PriceScheduleEnded     = "ended"
+	PriceScheduleCancelled = "cancelled"
+)
+
+// PriceChange is an append-only record of a product's price from
+// ChangedAt until the next change
+type PriceChange struct {
+	ID         uint        `gorm:"primarykey"`
+	ProductID  uint        `gorm:"not null;index:idx_price_changes_product,priority:1"`
+	Price      money.Money `gorm:"embedded;embeddedPrefix:price_"`
+	Source     string      `gorm:"not null"`
+	ChangedAt  time.Time   `gorm:"not null;index:idx_price_changes_product,priority:2"`
+	ScheduleID *uint
+}
+
+// PriceSchedule sets a product's price for a window, such as a sale. Its
+// currency must match the product's. BaseAmount is the price before the
+// schedule started and is restored when it ends, unless the price was
+// changed by hand in the meantime.
+type PriceSchedule struct {
+	gorm.Model
+	ProductID  uint        `gorm:"not null;index"`
+	Price      money.Money `gorm:"embedded;embeddedPrefix:price_"`
+	StartsAt   time.Time   `gorm:"not null;index"`
+	EndsAt     *time.Time  `gorm:"index"`
+	Status     string      `gorm:"not null;index"`
+	BaseAmount *int64
+}
+
+// PriceListEntry is a product's price in one currency's price list. It
+// is used instead of converting the product's own price.
+type PriceListEntry struct {
+	ID        uint   `gorm:"primarykey"`
+	ProductID uint   `gorm:"not null;uniqueIndex:idx_price_list_product_currency,priority:1"`
+	Currency  string `gorm:"size:3;not null;uniqueIndex:idx_price_list_product_currency,priority:2"`
+	Amount    int64  `gorm:"not null"`
+	UpdatedAt time.Time
+}
@@ -1,27 +1,108 @@
 package models
 
 import (
+	"product-management-system/pkg/money"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Product visibility levels. Unlisted products can be fetched by ID but are
+// left out of listings; private products are only visible to their owner.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// Image processing states recorded on Product.ImageStatus
+const (
+	ImageStatusPending    = "pending"
+	ImageStatusProcessing = "processing"
+	ImageStatusDone       = "done"
+	ImageStatusFailed     = "failed"
+)
+
+// Product is a catalogue entry. PriceMinAmount and PriceMaxAmount span the
+// prices of its variants in minor units of Price.Currency and are nil
+// while it has none. Stock and Reserved track inventory of products
+// without variants; Stock - Reserved is available.
 type Product struct {
 	gorm.Model
-	UserID                  uint   `gorm:"not null"`
-	ProductName             string `gorm:"not null"`
-	ProductDescription      string
-	ProductImages           []string `gorm:"type:text[]"`
-	CompressedProductImages []string `gorm:"type:text[]"`
-	ProductPrice            float64  `gorm:"type:decimal(10,2)"`
-	ProcessedAt             time.Time
+	UserID             uint   `gorm:"not null"`
+	ProductName        string `gorm:"not null"`
+	ProductDescription string
+	ProductImages      []string         `gorm:"type:text[]"`
+	ProcessedImages    []ProcessedImage `gorm:"type:jsonb;serializer:json"`
+	Price              money.Money      `gorm:"embedded;embeddedPrefix:price_"`
+	PriceMinAmount     *int64
+	PriceMaxAmount     *int64
+	Stock              int        `gorm:"not null;default:0"`
+	Reserved           int        `gorm:"not null;default:0"`
+	Visibility         string     `gorm:"not null;default:public;index"`
+	CategoryID         *uint      `gorm:"index"`
+	Tags               []string   `gorm:"type:text[];index:,type:gin"`
+	Attributes         Attributes `gorm:"type:jsonb;serializer:json;index:,type:gin"`
+	ImageStatus        string     `gorm:"index"`
+	ImageError         string
+	ProcessedAt        *time.Time
+}
+
+// Attributes are typed key/value product properties such as color or
+// weight. Values are strings, numbers or booleans.
+type Attributes map[string]interface{}
+
+// ProcessedImage holds the renditions generated from one source image,
+// along with the source's upright dimensions and EXIF details when present
+type ProcessedImage struct {
+	SourceURL      string
+	Variants       []ImageVariant
+	OriginalWidth  int
+	OriginalHeight int
+	CapturedAt     *time.Time
+	CameraModel    string
+}
+
+// ImageVariant is a single stored rendition of a source image
+type ImageVariant struct {
+	Name   string
+	URL    string
+	Width  int
+	Height int
+	Format string
+	Size   int
+}
+
+// ImageVariantSpec configures a rendition generated for every source
+// image. Zero Width and Height keep the original size; an empty Format
+// keeps the source format, except that GIFs are stored as PNG.
+type ImageVariantSpec struct {
+	Name    string
+	Width   uint
+	Height  uint
+	Fit     string
+	Quality int
+	Format  string
+}
+
+// URLs returns the URLs of every variant
+func (p ProcessedImage) URLs() []string {
+	urls := make([]string, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		urls = append(urls, v.URL)
+	}
+	return urls
 }
 
 type ImageProcessingTask struct {
-	ProductID           uint
-	ImageURLs           []string
-	ProcessedAt         time.Time
-	Status              string
-	ErrorMessage        string
-	CompressedImageURLs []string
+	ProductID uint
+	ImageURLs []string
+	// Append marks a task for a single image added to a product. Its result
+	// is merged with the product's other images and it skips garbage
+	// collection, which is only safe once all of a product's images are rebuilt.
+	Append          bool
+	ProcessedAt     time.Time
+	Status          string
+	ErrorMessage    string
+	ProcessedImages []ProcessedImage
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// QueuedTask is a task in the PostgreSQL task queue. Consumers lease due
+// tasks by pushing AvailableAt past the lease, delete them once processed
+// and set DeadLetteredAt when attempts run out.
+type QueuedTask struct {
+	ID             uint   `gorm:"primarykey"`
+	MessageID      string `gorm:"not null;uniqueIndex"`
+	Body           []byte `gorm:"not null"`
+	Attempts       int    `gorm:"not null;default:0"`
+	LastError      string
+	AvailableAt    time.Time  `gorm:"not null;index:idx_queued_tasks_due,where:dead_lettered_at IS NULL"`
+	DeadLetteredAt *time.Time `gorm:"index"`
+	CreatedAt      time.Time
+}
@@ -4,10 +4,16 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	gorm.Model
 	Username string `gorm:"unique;not null"`
 	Email    string `gorm:"unique;not null"`
-	Password string `gorm:"not null"`
+	Password string `gorm:"not null" json:"-"`
+	Role     string `gorm:"not null;default:user"`
 	Products []Product
 }
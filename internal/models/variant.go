@@ -0,0 +1,22 @@
+package models
+
+import "gorm.io/gorm"
+
+// ProductVariant is a purchasable version of a product, such as one size
+// and color of a shirt. PriceAmount is in minor units of the product's
+// currency; nil inherits the product's price. Stock is on hand and
+// Reserved is held by active reservations.
+type ProductVariant struct {
+	gorm.Model
+	ProductID   uint           `gorm:"not null;index"`
+	SKU         string         `gorm:"not null;uniqueIndex"`
+	Options     VariantOptions `gorm:"type:jsonb;serializer:json"`
+	Images      []string       `gorm:"type:text[]"`
+	Stock       int            `gorm:"not null;default:0"`
+	Reserved    int            `gorm:"not null;default:0"`
+	PriceAmount *int64
+}
+
+// VariantOptions maps option names to the variant's values, e.g.
+// {"size": "M", "color": "red"}
+type VariantOptions map[string]string
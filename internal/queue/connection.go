@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNotConnected   = errors.New("not connected to RabbitMQ")
+	ErrPublishNacked  = errors.New("RabbitMQ rejected the message")
+	ErrConfirmTimeout = errors.New("timed out waiting for RabbitMQ to confirm the message")
+)
+
+// ConnectionPolicy controls how clients reconnect to the broker and how
+// long publishers wait for confirmations
+type ConnectionPolicy struct {
+	ReconnectBaseDelay time.Duration
+	ReconnectMaxDelay  time.Duration
+	ConfirmTimeout     time.Duration
+}
+
+// ReconnectDelay returns the backoff before the given reconnection attempt
+// (1-based), doubling from ReconnectBaseDelay and capped at
+// ReconnectMaxDelay
+func (p ConnectionPolicy) ReconnectDelay(attempt int) time.Duration {
+	return RetryPolicy{BaseDelay: p.ReconnectBaseDelay, MaxDelay: p.ReconnectMaxDelay}.Delay(attempt)
+}
+
+// URL returns the AMQP address of a broker
+func URL(host string, port int) string {
+	return fmt.Sprintf("amqp://%s:%d", host, port)
+}
+
+// sleep waits for d, returning false early if ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
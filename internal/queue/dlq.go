@@ -0,0 +1,200 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"product-management-system/internal/models"
+	"slices"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var ErrEmptySelector = errors.New("select messages by ID, product ID or age, or select all")
+
+// DeadLetterMessage is a decoded view of a message in the dead-letter queue
+type DeadLetterMessage struct {
+	MessageID   string                      `json:"message_id"`
+	Task        *models.ImageProcessingTask `json:"task,omitempty"`
+	DecodeError string                      `json:"decode_error,omitempty"`
+	Error       string                      `json:"error"`
+	Attempts    int                         `json:"attempts"`
+	FailedAt    *time.Time                  `json:"failed_at,omitempty"`
+}
+
+// DeadLetterSelector picks dead-lettered messages. Set criteria are
+// combined with AND; an empty selector matches nothing unless All is set.
+type DeadLetterSelector struct {
+	MessageIDs []string
+	ProductID  uint
+	OlderThan  time.Duration
+	All        bool
+}
+
+func (s DeadLetterSelector) isEmpty() bool {
+	return !s.All && len(s.MessageIDs) == 0 && s.ProductID == 0 && s.OlderThan == 0
+}
+
+func (s DeadLetterSelector) matches(m *DeadLetterMessage, now time.Time) bool {
+	if s.All {
+		return true
+	}
+	if len(s.MessageIDs) > 0 && !slices.Contains(s.MessageIDs, m.MessageID) {
+		return false
+	}
+	if s.ProductID != 0 && (m.Task == nil || m.Task.ProductID != s.ProductID) {
+		return false
+	}
+	if s.OlderThan != 0 && (m.FailedAt == nil || now.Sub(*m.FailedAt) < s.OlderThan) {
+		return false
+	}
+	return true
+}
+
+// ListDeadLetters returns up to limit dead-lettered messages without
+// removing them from the queue
+func (r *RabbitMQQueue) ListDeadLetters(limit int) ([]DeadLetterMessage, error) {
+	messages := []DeadLetterMessage{}
+	err := r.scanDeadLetters(func(ch *amqp.Channel, d amqp.Delivery, m *DeadLetterMessage) (bool, error) {
+		messages = append(messages, *m)
+		return limit <= 0 || len(messages) < limit, nil
+	})
+	return messages, err
+}
+
+// ReplayDeadLetters moves the selected messages back onto the work queue
+// with a fresh attempt count and returns how many were replayed
+func (r *RabbitMQQueue) ReplayDeadLetters(selector DeadLetterSelector) (int, error) {
+	if selector.isEmpty() {
+		return 0, ErrEmptySelector
+	}
+
+	now := time.Now()
+	replayed := 0
+	err := r.scanDeadLetters(func(ch *amqp.Channel, d amqp.Delivery, m *DeadLetterMessage) (bool, error) {
+		if !selector.matches(m, now) {
+			return true, nil
+		}
+
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		delete(headers, HeaderAttempts)
+
+		// Only drop the dead letter once the broker has confirmed the copy
+		if err := r.publish(context.Background(), "", ImageProcessingQueue, amqp.Publishing{
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    d.MessageId,
+			Headers:      headers,
+			Body:         d.Body,
+		}); err != nil {
+			return false, fmt.Errorf("failed to replay message %s: %w", m.MessageID, err)
+		}
+		if err := d.Ack(false); err != nil {
+			return false, err
+		}
+		replayed++
+		return true, nil
+	})
+	return replayed, err
+}
+
+// PurgeDeadLetters permanently deletes the selected messages and returns
+// how many were removed
+func (r *RabbitMQQueue) PurgeDeadLetters(selector DeadLetterSelector) (int, error) {
+	if selector.isEmpty() {
+		return 0, ErrEmptySelector
+	}
+
+	now := time.Now()
+	purged := 0
+	err := r.scanDeadLetters(func(ch *amqp.Channel, d amqp.Delivery, m *DeadLetterMessage) (bool, error) {
+		if !selector.matches(m, now) {
+			return true, nil
+		}
+		if err := d.Ack(false); err != nil {
+			return false, err
+		}
+		purged++
+		return true, nil
+	})
+	return purged, err
+}
+
+// scanDeadLetters fetches each message currently in the dead-letter queue
+// on a dedicated channel and hands it to visit until visit returns false.
+// Messages visit doesn't ack are returned to the queue when the channel
+// closes.
+func (r *RabbitMQQueue) scanDeadLetters(visit func(*amqp.Channel, amqp.Delivery, *DeadLetterMessage) (bool, error)) error {
+	conn, err := r.connection()
+	if err != nil {
+		return err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %w", err)
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueInspect(DeadLetterQueue)
+	if err != nil {
+		return fmt.Errorf("failed to inspect dead-letter queue: %w", err)
+	}
+
+	// Only visit messages present at the start so the scan terminates
+	for i := 0; i < q.Messages; i++ {
+		d, ok, err := ch.Get(DeadLetterQueue, false)
+		if err != nil {
+			return fmt.Errorf("failed to fetch dead-lettered message: %w", err)
+		}
+		if !ok {
+			return nil
+		}
+
+		more, err := visit(ch, d, decodeDeadLetter(d))
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func decodeDeadLetter(d amqp.Delivery) *DeadLetterMessage {
+	var failedAt *time.Time
+	if value, ok := d.Headers[HeaderFailedAt].(string); ok {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			failedAt = &t
+		}
+	}
+	lastError, _ := d.Headers[HeaderLastError].(string)
+
+	return newDeadLetterMessage(d.MessageId, d.Body, Attempts(d), lastError, failedAt)
+}
+
+// newDeadLetterMessage decodes the task in a dead-lettered body
+func newDeadLetterMessage(messageID string, body []byte, attempts int, lastError string, failedAt *time.Time) *DeadLetterMessage {
+	m := &DeadLetterMessage{
+		MessageID: messageID,
+		Error:     lastError,
+		Attempts:  attempts,
+		FailedAt:  failedAt,
+	}
+
+	task := &models.ImageProcessingTask{}
+	if err := json.Unmarshal(body, task); err != nil {
+		m.DecodeError = err.Error()
+	} else {
+		m.Task = task
+	}
+
+	return m
+}
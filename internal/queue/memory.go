@@ -0,0 +1,262 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryQueue is an in-process TaskQueue for tests and single-process
+// deployments. Tasks are lost when the process exits.
+type MemoryQueue struct {
+	retryPolicy RetryPolicy
+
+	mu          sync.Mutex
+	pending     []*memoryMessage
+	deadLetters []*memoryMessage
+	// ready is signalled when a task becomes pending
+	ready  chan struct{}
+	timers map[*time.Timer]struct{}
+	closed bool
+}
+
+type memoryMessage struct {
+	messageID string
+	body      []byte
+	attempts  int
+	lastError string
+	failedAt  *time.Time
+}
+
+func NewMemoryQueue(retryPolicy RetryPolicy) *MemoryQueue {
+	return &MemoryQueue{
+		retryPolicy: retryPolicy,
+		ready:       make(chan struct{}, 1),
+		timers:      make(map[*time.Timer]struct{}),
+	}
+}
+
+// Publish fails with ErrQueueClosed once the queue is closed, so callers
+// such as the outbox relay keep the task
+func (q *MemoryQueue) Publish(ctx context.Context, body []byte, messageID string) error {
+	return q.push(&memoryMessage{messageID: messageID, body: body})
+}
+
+// push makes a message pending and wakes a consumer
+func (q *MemoryQueue) push(m *memoryMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.pending = append(q.pending, m)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pop takes the oldest pending message, if any
+func (q *MemoryQueue) pop() *memoryMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return nil
+	}
+	m := q.pending[0]
+	q.pending = q.pending[1:]
+
+	// Pass the wake-up on to other consumers
+	if len(q.pending) > 0 {
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+	return m
+}
+
+func (q *MemoryQueue) Consume(ctx context.Context, prefetch int) (<-chan Delivery, error) {
+	out := make(chan Delivery)
+	slots := make(chan struct{}, prefetch)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case slots <- struct{}{}:
+			}
+
+			m := q.pop()
+			for m == nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-q.ready:
+				}
+				m = q.pop()
+			}
+
+			d := &memoryDelivery{queue: q, message: m, release: func() { <-slots }}
+			select {
+			case out <- d:
+			case <-ctx.Done():
+				// Lost if the queue was closed meanwhile, like the rest
+				q.push(m)
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Close drops pending tasks and stops scheduled retries
+func (q *MemoryQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.pending = nil
+	for timer := range q.timers {
+		timer.Stop()
+	}
+	q.timers = nil
+}
+
+// retryLater makes a message pending again after delay
+func (q *MemoryQueue) retryLater(m *memoryMessage, delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		delete(q.timers, timer)
+		q.mu.Unlock()
+		q.push(m)
+	})
+	q.timers[timer] = struct{}{}
+	return nil
+}
+
+func (q *MemoryQueue) deadLetter(m *memoryMessage, cause error) {
+	now := time.Now()
+	m.lastError = cause.Error()
+	m.failedAt = &now
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deadLetters = append(q.deadLetters, m)
+}
+
+func (q *MemoryQueue) ListDeadLetters(limit int) ([]DeadLetterMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages := []DeadLetterMessage{}
+	for _, m := range q.deadLetters {
+		if limit > 0 && len(messages) >= limit {
+			break
+		}
+		messages = append(messages, *m.decode())
+	}
+	return messages, nil
+}
+
+func (q *MemoryQueue) ReplayDeadLetters(selector DeadLetterSelector) (int, error) {
+	replayed, err := q.removeDeadLetters(selector)
+	for i, m := range replayed {
+		m.attempts = 0
+		if err := q.push(m); err != nil {
+			// Keep what couldn't be replayed
+			q.mu.Lock()
+			q.deadLetters = append(q.deadLetters, replayed[i:]...)
+			q.mu.Unlock()
+			return i, err
+		}
+	}
+	return len(replayed), err
+}
+
+func (q *MemoryQueue) PurgeDeadLetters(selector DeadLetterSelector) (int, error) {
+	purged, err := q.removeDeadLetters(selector)
+	return len(purged), err
+}
+
+// removeDeadLetters takes the selected messages out of the dead-letter
+// queue
+func (q *MemoryQueue) removeDeadLetters(selector DeadLetterSelector) ([]*memoryMessage, error) {
+	if selector.isEmpty() {
+		return nil, ErrEmptySelector
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	var removed []*memoryMessage
+	kept := q.deadLetters[:0]
+	for _, m := range q.deadLetters {
+		if selector.matches(m.decode(), now) {
+			removed = append(removed, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	q.deadLetters = kept
+	return removed, nil
+}
+
+func (m *memoryMessage) decode() *DeadLetterMessage {
+	return newDeadLetterMessage(m.messageID, m.body, m.attempts, m.lastError, m.failedAt)
+}
+
+type memoryDelivery struct {
+	queue   *MemoryQueue
+	message *memoryMessage
+	release func()
+	once    sync.Once
+}
+
+func (d *memoryDelivery) Body() []byte      { return d.message.body }
+func (d *memoryDelivery) MessageID() string { return d.message.messageID }
+func (d *memoryDelivery) Attempts() int     { return d.message.attempts }
+
+func (d *memoryDelivery) Ack() error {
+	d.once.Do(d.release)
+	return nil
+}
+
+func (d *memoryDelivery) Nack() error {
+	d.once.Do(d.release)
+	return d.queue.push(d.message)
+}
+
+func (d *memoryDelivery) Retry(cause error) (bool, error) {
+	d.once.Do(d.release)
+
+	d.message.attempts++
+	if d.message.attempts >= d.queue.retryPolicy.MaxAttempts {
+		d.queue.deadLetter(d.message, cause)
+		return true, nil
+	}
+	d.message.lastError = cause.Error()
+	return false, d.queue.retryLater(d.message, d.queue.retryPolicy.Delay(d.message.attempts))
+}
+
+func (d *memoryDelivery) DeadLetter(cause error) error {
+	d.once.Do(d.release)
+
+	d.message.attempts++
+	d.queue.deadLetter(d.message, cause)
+	return nil
+}
@@ -0,0 +1,283 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresQueue is a TaskQueue stored in the queued_tasks table, for
+// deployments without a broker. Consumers claim due tasks with
+// SELECT ... FOR UPDATE SKIP LOCKED and hide them for the lease, so any
+// number of image processors can consume concurrently. A task that isn't
+// settled within its lease, e.g. because its consumer died, is delivered
+// again.
+type PostgresQueue struct {
+	db           *gorm.DB
+	retryPolicy  RetryPolicy
+	pollInterval time.Duration
+	lease        time.Duration
+	logger       *logger.Logger
+}
+
+// NewPostgresQueue creates the queued_tasks table if needed
+func NewPostgresQueue(db *gorm.DB, retryPolicy RetryPolicy, pollInterval, lease time.Duration, logger *logger.Logger) (*PostgresQueue, error) {
+	if db == nil {
+		return nil, errors.New("postgres queue needs a database")
+	}
+	if err := db.AutoMigrate(&models.QueuedTask{}); err != nil {
+		return nil, err
+	}
+	return &PostgresQueue{
+		db:           db,
+		retryPolicy:  retryPolicy,
+		pollInterval: pollInterval,
+		lease:        lease,
+		logger:       logger,
+	}, nil
+}
+
+// Publish inserts the task. A task whose message ID is already queued is
+// ignored, so republishing is idempotent.
+func (q *PostgresQueue) Publish(ctx context.Context, body []byte, messageID string) error {
+	return q.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "message_id"}}, DoNothing: true}).
+		Create(&models.QueuedTask{
+			MessageID:   messageID,
+			Body:        body,
+			AvailableAt: time.Now(),
+		}).Error
+}
+
+func (q *PostgresQueue) Consume(ctx context.Context, prefetch int) (<-chan Delivery, error) {
+	out := make(chan Delivery)
+	slots := make(chan struct{}, prefetch)
+
+	go func() {
+		defer close(out)
+
+		for {
+			// Wait for a free slot, then claim as many tasks as there are
+			select {
+			case <-ctx.Done():
+				return
+			case slots <- struct{}{}:
+			}
+			free := 1
+			for free < prefetch && len(slots) < cap(slots) {
+				slots <- struct{}{}
+				free++
+			}
+
+			// Failed claims are retried on the next poll
+			tasks, err := q.claim(ctx, free)
+			if err != nil && ctx.Err() == nil {
+				q.logger.Error("Failed to claim queued tasks", "error", err)
+			}
+			for i := len(tasks); i < free; i++ {
+				<-slots
+			}
+
+			for i := range tasks {
+				d := &postgresDelivery{queue: q, task: tasks[i], release: func() { <-slots }}
+				select {
+				case out <- d:
+				case <-ctx.Done():
+					// Make the unsent tasks available to other consumers
+					for _, task := range tasks[i:] {
+						q.release(&task)
+					}
+					return
+				}
+			}
+
+			if len(tasks) == 0 && !sleep(ctx, q.pollInterval) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// claim leases up to limit due tasks, oldest first. Each returned task's
+// AvailableAt is its lease, which settling it checks.
+func (q *PostgresQueue) claim(ctx context.Context, limit int) ([]models.QueuedTask, error) {
+	now := time.Now()
+	var tasks []models.QueuedTask
+	err := q.db.WithContext(ctx).Raw(`
+		UPDATE queued_tasks SET available_at = ?
+		WHERE id IN (
+			SELECT id FROM queued_tasks
+			WHERE dead_lettered_at IS NULL AND available_at <= ?
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(q.lease), now, limit).
+		Scan(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING doesn't preserve the subquery's order
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+// leased scopes a query to a task while it is still under the lease it
+// was claimed with
+func (q *PostgresQueue) leased(task *models.QueuedTask) *gorm.DB {
+	return q.db.Model(&models.QueuedTask{}).
+		Where("id = ? AND available_at = ? AND dead_lettered_at IS NULL", task.ID, task.AvailableAt)
+}
+
+// settled reports ErrLeaseLost for updates that matched no leased task
+func settled(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// release makes a leased task available again right away
+func (q *PostgresQueue) release(task *models.QueuedTask) error {
+	return settled(q.leased(task).Update("available_at", time.Now()))
+}
+
+// Close is a no-op; the database is owned by the caller
+func (q *PostgresQueue) Close() {}
+
+// ListDeadLetters returns up to limit dead-lettered tasks, oldest first
+func (q *PostgresQueue) ListDeadLetters(limit int) ([]DeadLetterMessage, error) {
+	query := q.db.Where("dead_lettered_at IS NOT NULL").Order("dead_lettered_at, id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var tasks []models.QueuedTask
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	messages := make([]DeadLetterMessage, len(tasks))
+	for i := range tasks {
+		messages[i] = *decodeQueuedTask(&tasks[i])
+	}
+	return messages, nil
+}
+
+// ReplayDeadLetters makes the selected tasks due again with a fresh
+// attempt count
+func (q *PostgresQueue) ReplayDeadLetters(selector DeadLetterSelector) (int, error) {
+	ids, err := q.selectDeadLetters(selector)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := q.db.Model(&models.QueuedTask{}).
+		Where("id IN ? AND dead_lettered_at IS NOT NULL", ids).
+		Updates(map[string]interface{}{
+			"attempts":         0,
+			"dead_lettered_at": nil,
+			"available_at":     time.Now(),
+		})
+	return int(result.RowsAffected), result.Error
+}
+
+// PurgeDeadLetters deletes the selected tasks
+func (q *PostgresQueue) PurgeDeadLetters(selector DeadLetterSelector) (int, error) {
+	ids, err := q.selectDeadLetters(selector)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := q.db.Where("id IN ? AND dead_lettered_at IS NOT NULL", ids).Delete(&models.QueuedTask{})
+	return int(result.RowsAffected), result.Error
+}
+
+// selectDeadLetters returns the IDs of dead-lettered tasks matching
+// selector. Product IDs live in the payload, so tasks are matched in Go.
+func (q *PostgresQueue) selectDeadLetters(selector DeadLetterSelector) ([]uint, error) {
+	if selector.isEmpty() {
+		return nil, ErrEmptySelector
+	}
+
+	var tasks []models.QueuedTask
+	if err := q.db.Where("dead_lettered_at IS NOT NULL").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var ids []uint
+	for i := range tasks {
+		if selector.matches(decodeQueuedTask(&tasks[i]), now) {
+			ids = append(ids, tasks[i].ID)
+		}
+	}
+	return ids, nil
+}
+
+func decodeQueuedTask(task *models.QueuedTask) *DeadLetterMessage {
+	return newDeadLetterMessage(task.MessageID, task.Body, task.Attempts, task.LastError, task.DeadLetteredAt)
+}
+
+type postgresDelivery struct {
+	queue   *PostgresQueue
+	task    models.QueuedTask
+	release func()
+	once    sync.Once
+}
+
+func (d *postgresDelivery) Body() []byte      { return d.task.Body }
+func (d *postgresDelivery) MessageID() string { return d.task.MessageID }
+func (d *postgresDelivery) Attempts() int     { return d.task.Attempts }
+
+func (d *postgresDelivery) Ack() error {
+	defer d.once.Do(d.release)
+	return settled(d.queue.leased(&d.task).Delete(&models.QueuedTask{}))
+}
+
+func (d *postgresDelivery) Nack() error {
+	defer d.once.Do(d.release)
+	return d.queue.release(&d.task)
+}
+
+func (d *postgresDelivery) Retry(cause error) (bool, error) {
+	defer d.once.Do(d.release)
+
+	attempts := d.task.Attempts + 1
+	if attempts >= d.queue.retryPolicy.MaxAttempts {
+		return true, d.deadLetter(attempts, cause)
+	}
+
+	result := d.queue.leased(&d.task).Updates(map[string]interface{}{
+		"attempts":     attempts,
+		"last_error":   cause.Error(),
+		"available_at": time.Now().Add(d.queue.retryPolicy.Delay(attempts)),
+	})
+	return false, settled(result)
+}
+
+func (d *postgresDelivery) DeadLetter(cause error) error {
+	defer d.once.Do(d.release)
+	return d.deadLetter(d.task.Attempts+1, cause)
+}
+
+func (d *postgresDelivery) deadLetter(attempts int, cause error) error {
+	return settled(d.queue.leased(&d.task).Updates(map[string]interface{}{
+		"attempts":         attempts,
+		"last_error":       cause.Error(),
+		"dead_lettered_at": time.Now(),
+	}))
+}
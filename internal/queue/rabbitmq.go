@@ -1,74 +1,226 @@
 package queue
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
-	"product-management-system/internal/models"
+	"product-management-system/pkg/logger"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 )
 
+// RabbitMQQueue is a TaskQueue that publishes with publisher confirms.
+// It connects in the background and reconnects with backoff whenever the
+// connection or channel closes, declaring the topology again each time.
+// Publishing while disconnected fails with ErrNotConnected.
 type RabbitMQQueue struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   amqp.Queue
+	url         string
+	retryPolicy RetryPolicy
+	policy      ConnectionPolicy
+	logger      *logger.Logger
+
+	// mu guards the connection and serializes publishes, so the next
+	// confirmation on the channel is always for the message just published
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	// connected is closed while a connection is up and replaced when it
+	// is lost
+	connected chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRabbitMQQueue(host string, port int, retryPolicy RetryPolicy, policy ConnectionPolicy, logger *logger.Logger) *RabbitMQQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &RabbitMQQueue{
+		url:         URL(host, port),
+		retryPolicy: retryPolicy,
+		policy:      policy,
+		logger:      logger,
+		connected:   make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go r.maintain()
+	return r
+}
+
+// WaitConnected blocks until the queue is connected or ctx is done
+func (r *RabbitMQQueue) WaitConnected(ctx context.Context) error {
+	r.mu.Lock()
+	connected := r.connected
+	r.mu.Unlock()
+
+	select {
+	case <-connected:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrNotConnected, ctx.Err())
+	}
 }
 
-func NewRabbitMQQueue(host string, port int) *RabbitMQQueue {
-	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%d", host, port))
+// maintain connects, waits for the connection or channel to close and
+// reconnects with backoff until Close is called
+func (r *RabbitMQQueue) maintain() {
+	defer close(r.done)
+
+	failures := 0
+	for {
+		if failures > 0 && !sleep(r.ctx, r.policy.ReconnectDelay(failures)) {
+			return
+		}
+
+		connClosed, channelClosed, err := r.connect()
+		if err != nil {
+			failures++
+			r.logger.Warn("Failed to connect to RabbitMQ", "attempt", failures, "error", err)
+			continue
+		}
+		failures = 0
+		r.logger.Info("Connected to RabbitMQ")
+
+		var cause *amqp.Error
+		select {
+		case <-r.ctx.Done():
+			return
+		case cause = <-connClosed:
+		case cause = <-channelClosed:
+		}
+
+		// Reconnect immediately; repeated failures back off
+		r.disconnect()
+		r.logger.Error("Lost RabbitMQ connection, reconnecting", "error", cause)
+	}
+}
+
+// connect dials the broker, opens a channel in confirm mode and declares
+// the topology. It returns channels that receive when the connection or
+// the channel closes.
+func (r *RabbitMQQueue) connect() (<-chan *amqp.Error, <-chan *amqp.Error, error) {
+	conn, err := amqp.Dial(r.url)
 	if err != nil {
-		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+		return nil, nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
-		log.Fatalf("Failed to open a channel: %v", err)
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to open a channel: %w", err)
 	}
 
-	q, err := ch.QueueDeclare(
-		"image_processing_queue", // name
-		true,                     // durable
-		false,                    // delete when unused
-		false,                    // exclusive
-		false,                    // no-wait
-		nil,                      // arguments
-	)
-	if err != nil {
-		log.Fatalf("Failed to declare a queue: %v", err)
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
 	}
 
-	return &RabbitMQQueue{
-		conn:    conn,
-		channel: ch,
-		queue:   q,
+	if _, err := DeclareTopology(ch, r.retryPolicy); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to declare queues: %w", err)
 	}
+
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	r.mu.Lock()
+	r.conn = conn
+	r.channel = ch
+	r.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	close(r.connected)
+	r.mu.Unlock()
+
+	return connClosed, channelClosed, nil
 }
 
-func (r *RabbitMQQueue) EnqueueImageProcessing(task *models.ImageProcessingTask) error {
-	body, err := json.Marshal(task)
-	if err != nil {
-		return fmt.Errorf("failed to marshal task: %w", err)
+// disconnect drops the current connection so publishes fail fast until
+// the next one is up
+func (r *RabbitMQQueue) disconnect() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil {
+		r.conn.Close()
 	}
+	r.conn = nil
+	r.channel = nil
+	r.confirms = nil
+	r.connected = make(chan struct{})
+}
+
+// connection returns the current connection, or ErrNotConnected
+func (r *RabbitMQQueue) connection() (*amqp.Connection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	err = r.channel.Publish(
-		"",           // exchange
-		r.queue.Name, // routing key
-		false,        // mandatory
-		false,        // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
+	if r.conn == nil {
+		return nil, ErrNotConnected
+	}
+	return r.conn, nil
+}
+
+// Publish publishes an encoded task and returns once the broker has
+// confirmed it
+func (r *RabbitMQQueue) Publish(ctx context.Context, body []byte, messageID string) error {
+	return r.publish(ctx, "", ImageProcessingQueue, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Body:         body,
+	})
+}
+
+// publish publishes msg on the confirm-mode channel and waits for the
+// broker's confirmation
+func (r *RabbitMQQueue) publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.channel == nil {
+		return ErrNotConnected
+	}
+
+	err := r.channel.Publish(
+		exchange, // exchange
+		key,      // routing key
+		false,    // mandatory
+		false,    // immediate
+		msg,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
-	return nil
+	timer := time.NewTimer(r.policy.ConfirmTimeout)
+	defer timer.Stop()
+
+	select {
+	case confirm, ok := <-r.confirms:
+		if !ok {
+			return fmt.Errorf("connection closed before the message was confirmed: %w", ErrNotConnected)
+		}
+		if !confirm.Ack {
+			return ErrPublishNacked
+		}
+		return nil
+	case <-timer.C:
+		// A late confirmation would be taken for the next message's, so
+		// force a fresh channel
+		r.channel.Close()
+		return ErrConfirmTimeout
+	case <-ctx.Done():
+		r.channel.Close()
+		return ctx.Err()
+	}
 }
 
+// Close stops reconnecting and closes the connection
 func (r *RabbitMQQueue) Close() {
-	r.channel.Close()
-	r.conn.Close()
+	r.cancel()
+	<-r.done
+	r.disconnect()
 }
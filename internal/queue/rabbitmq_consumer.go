@@ -0,0 +1,175 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Consume delivers tasks from the work queue. Each session runs on its own
+// channel of the current connection; when the connection is lost,
+// consuming resumes once it is re-established. Deliveries whose acks were
+// lost with their channel are redelivered by the broker.
+func (r *RabbitMQQueue) Consume(ctx context.Context, prefetch int) (<-chan Delivery, error) {
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+
+		failures := 0
+		for {
+			if failures > 0 && !sleep(ctx, r.policy.ReconnectDelay(failures)) {
+				return
+			}
+			if err := r.WaitConnected(ctx); err != nil {
+				return
+			}
+
+			consumed, err := r.consume(ctx, prefetch, out)
+			if ctx.Err() != nil {
+				return
+			}
+			if consumed {
+				failures = 0
+				r.logger.Error("Stopped consuming from RabbitMQ, resuming", "error", err)
+				continue
+			}
+			failures++
+			r.logger.Warn("Failed to consume from RabbitMQ", "attempt", failures, "error", err)
+		}
+	}()
+	return out, nil
+}
+
+// consume runs one consumer session until its channel closes or ctx is
+// cancelled. It reports whether consuming started, and why it stopped.
+func (r *RabbitMQQueue) consume(ctx context.Context, prefetch int, out chan<- Delivery) (bool, error) {
+	conn, err := r.connection()
+	if err != nil {
+		return false, err
+	}
+
+	// The channel stays open after ctx is cancelled so unsettled
+	// deliveries can still be acked; it closes with the connection
+	ch, err := conn.Channel()
+	if err != nil {
+		return false, fmt.Errorf("failed to open a channel: %w", err)
+	}
+
+	// Limit unacked deliveries so each worker holds a bounded number of tasks
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		ch.Close()
+		return false, fmt.Errorf("failed to set channel QoS: %w", err)
+	}
+
+	tag := "image-processor-" + newMessageID()
+	msgs, err := ch.Consume(
+		ImageProcessingQueue, // queue
+		tag,                  // consumer
+		false,                // auto-ack
+		false,                // exclusive
+		false,                // no-local
+		false,                // no-wait
+		nil,                  // args
+	)
+	if err != nil {
+		ch.Close()
+		return false, fmt.Errorf("failed to register a consumer: %w", err)
+	}
+
+	for {
+		select {
+		case d, ok := <-msgs:
+			if !ok {
+				return true, errors.New("delivery channel closed")
+			}
+			select {
+			case out <- &amqpDelivery{queue: r, d: d}:
+			case <-ctx.Done():
+				d.Nack(false, true)
+			}
+		case <-ctx.Done():
+			// Stop new deliveries and return the prefetched ones
+			if err := ch.Cancel(tag, false); err != nil {
+				return true, err
+			}
+			for d := range msgs {
+				d.Nack(false, true)
+			}
+			return true, nil
+		}
+	}
+}
+
+// amqpDelivery settles a delivery on the channel it arrived on. Retried
+// and dead-lettered copies are published on the queue's confirm-mode
+// channel, and the delivery is only acked once the broker has confirmed
+// the copy.
+type amqpDelivery struct {
+	queue *RabbitMQQueue
+	d     amqp.Delivery
+}
+
+func (d *amqpDelivery) Body() []byte      { return d.d.Body }
+func (d *amqpDelivery) MessageID() string { return d.d.MessageId }
+func (d *amqpDelivery) Attempts() int     { return Attempts(d.d) }
+func (d *amqpDelivery) Ack() error        { return d.d.Ack(false) }
+func (d *amqpDelivery) Nack() error       { return d.d.Nack(false, true) }
+
+// Retry schedules the delivery for another attempt after the policy's
+// backoff, or routes it to the dead-letter queue with cause attached once
+// attempts are exhausted
+func (d *amqpDelivery) Retry(cause error) (bool, error) {
+	attempts := Attempts(d.d) + 1
+	if attempts >= d.queue.retryPolicy.MaxAttempts {
+		return true, d.DeadLetter(cause)
+	}
+
+	delay := d.queue.retryPolicy.Delay(attempts)
+	return false, d.republish("", retryQueueName(attempts), attempts, cause, delay)
+}
+
+// DeadLetter routes the delivery straight to the dead-letter queue, e.g.
+// for messages that can never succeed such as malformed payloads
+func (d *amqpDelivery) DeadLetter(cause error) error {
+	return d.republish(DeadLetterExchange, ImageProcessingQueue, Attempts(d.d)+1, cause, 0)
+}
+
+// republish publishes a copy of the delivery with its attempt count and
+// cause and acks the original. A positive delay expires the copy from its
+// delay queue after that long.
+func (d *amqpDelivery) republish(exchange, key string, attempts int, cause error, delay time.Duration) error {
+	headers := amqp.Table{}
+	for k, v := range d.d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderAttempts] = int32(attempts)
+	headers[HeaderLastError] = cause.Error()
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	// Dead-lettered messages are addressed by ID from the admin tools
+	messageID := d.d.MessageId
+	if messageID == "" {
+		messageID = newMessageID()
+	}
+
+	msg := amqp.Publishing{
+		ContentType:  d.d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Headers:      headers,
+		Body:         d.d.Body,
+	}
+	if delay > 0 {
+		msg.Expiration = strconv.FormatInt(delay.Milliseconds(), 10)
+	}
+
+	// Publishing waits at most for the confirm timeout
+	if err := d.queue.publish(context.Background(), exchange, key, msg); err != nil {
+		return fmt.Errorf("failed to republish message: %w", err)
+	}
+	return d.d.Ack(false)
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-management-system/pkg/logger"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Queue backends selectable with queue.backend
+const (
+	BackendRabbitMQ = "rabbitmq"
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+var (
+	ErrQueueClosed = errors.New("task queue is closed")
+	ErrLeaseLost   = errors.New("task lease expired and the task may have been delivered again")
+)
+
+// TaskQueue carries encoded image processing tasks from the API to the
+// image workers. Failed tasks are retried with the RetryPolicy's backoff
+// and dead-lettered once attempts run out.
+type TaskQueue interface {
+	DeadLetters
+
+	// Publish stores a task; the RabbitMQ and PostgreSQL backends return
+	// once it is durable. messageID identifies the task across retries and
+	// in the dead-letter queue.
+	Publish(ctx context.Context, body []byte, messageID string) error
+	// Consume delivers tasks, with at most prefetch unsettled at once,
+	// until ctx is cancelled. The channel is closed once nothing more will
+	// be delivered; tasks still unsettled can be settled after that.
+	Consume(ctx context.Context, prefetch int) (<-chan Delivery, error)
+	Close()
+}
+
+// Delivery is a task handed to a consumer. It is settled by calling
+// exactly one of Ack, Nack, Retry or DeadLetter. Settling a PostgreSQL
+// task whose lease has run out fails with ErrLeaseLost and leaves the task
+// to whoever claimed it next.
+type Delivery interface {
+	Body() []byte
+	MessageID() string
+	// Attempts returns how many times the task was processed before
+	Attempts() int
+	// Ack removes the task from the queue
+	Ack() error
+	// Nack returns the task to the queue for immediate redelivery without
+	// counting an attempt
+	Nack() error
+	// Retry schedules the task for another attempt after the retry
+	// policy's backoff, or dead-letters it with cause once attempts are
+	// exhausted. It reports whether the task was dead-lettered.
+	Retry(cause error) (bool, error)
+	// DeadLetter moves the task straight to the dead-letter queue, e.g.
+	// for payloads that can never succeed
+	DeadLetter(cause error) error
+}
+
+// DeadLetters inspects, replays and purges dead-lettered tasks
+type DeadLetters interface {
+	ListDeadLetters(limit int) ([]DeadLetterMessage, error)
+	ReplayDeadLetters(selector DeadLetterSelector) (int, error)
+	PurgeDeadLetters(selector DeadLetterSelector) (int, error)
+}
+
+// Config selects and configures a TaskQueue backend
+type Config struct {
+	Backend     string
+	RetryPolicy RetryPolicy
+
+	// RabbitMQ
+	Host             string
+	Port             int
+	ConnectionPolicy ConnectionPolicy
+
+	// PostgreSQL
+	DB *gorm.DB
+	// PollInterval is how often idle consumers look for due tasks
+	PollInterval time.Duration
+	// Lease is how long a delivered task is hidden from other consumers;
+	// tasks not settled within it are delivered again
+	Lease time.Duration
+}
+
+// New builds the backend named by cfg.Backend, defaulting to RabbitMQ
+func New(cfg Config, logger *logger.Logger) (TaskQueue, error) {
+	switch cfg.Backend {
+	case "", BackendRabbitMQ:
+		return NewRabbitMQQueue(cfg.Host, cfg.Port, cfg.RetryPolicy, cfg.ConnectionPolicy, logger), nil
+	case BackendPostgres:
+		return NewPostgresQueue(cfg.DB, cfg.RetryPolicy, cfg.PollInterval, cfg.Lease, logger)
+	case BackendMemory:
+		return NewMemoryQueue(cfg.RetryPolicy), nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,269 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"product-management-system/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// deliveryTimeout bounds how long tests wait for a delivery
+const deliveryTimeout = 2 * time.Second
+
+var testPolicy = RetryPolicy{MaxAttempts: 2, BaseDelay: 20 * time.Millisecond, MaxDelay: time.Second}
+
+func testLogger() *logger.Logger {
+	return &logger.Logger{SugaredLogger: zap.NewNop().Sugar()}
+}
+
+func newTestMemoryQueue(t *testing.T) TaskQueue {
+	q := NewMemoryQueue(testPolicy)
+	t.Cleanup(q.Close)
+	return q
+}
+
+// newTestPostgresQueue returns a queue on the PostgreSQL database in
+// TEST_DATABASE_DSN, skipping the test when it isn't set
+func newTestPostgresQueue(t *testing.T, lease time.Duration) TaskQueue {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	q, err := NewPostgresQueue(db, testPolicy, 10*time.Millisecond, lease, testLogger())
+	if err != nil {
+		t.Fatalf("NewPostgresQueue: %v", err)
+	}
+	if err := db.Exec("TRUNCATE queued_tasks RESTART IDENTITY").Error; err != nil {
+		t.Fatalf("failed to empty queued_tasks: %v", err)
+	}
+	return q
+}
+
+func TestMemoryQueue(t *testing.T) {
+	testTaskQueue(t, newTestMemoryQueue)
+}
+
+func TestPostgresQueue(t *testing.T) {
+	testTaskQueue(t, func(t *testing.T) TaskQueue { return newTestPostgresQueue(t, time.Minute) })
+}
+
+// testTaskQueue checks the behaviour every TaskQueue backend shares
+func testTaskQueue(t *testing.T, newQueue func(*testing.T) TaskQueue) {
+	t.Run("delivers in order until acked", func(t *testing.T) {
+		q := newQueue(t)
+		deliveries := consume(t, q, 10)
+
+		for i := 1; i <= 3; i++ {
+			publish(t, q, i)
+		}
+		for i := 1; i <= 3; i++ {
+			d := receive(t, deliveries)
+			if d.MessageID() != messageID(i) || string(d.Body()) != body(i) || d.Attempts() != 0 {
+				t.Fatalf("delivery %d = %s %s (attempts %d)", i, d.MessageID(), d.Body(), d.Attempts())
+			}
+			if err := d.Ack(); err != nil {
+				t.Fatalf("Ack: %v", err)
+			}
+		}
+		expectNone(t, deliveries)
+	})
+
+	t.Run("nack redelivers", func(t *testing.T) {
+		q := newQueue(t)
+		deliveries := consume(t, q, 1)
+
+		publish(t, q, 1)
+		if err := receive(t, deliveries).Nack(); err != nil {
+			t.Fatalf("Nack: %v", err)
+		}
+		d := receive(t, deliveries)
+		if d.MessageID() != messageID(1) || d.Attempts() != 0 {
+			t.Fatalf("redelivery = %s (attempts %d)", d.MessageID(), d.Attempts())
+		}
+		d.Ack()
+	})
+
+	t.Run("prefetch bounds unsettled deliveries", func(t *testing.T) {
+		q := newQueue(t)
+		deliveries := consume(t, q, 1)
+
+		publish(t, q, 1)
+		publish(t, q, 2)
+		first := receive(t, deliveries)
+		expectNone(t, deliveries)
+		first.Ack()
+		if d := receive(t, deliveries); d.MessageID() != messageID(2) {
+			t.Fatalf("second delivery = %s", d.MessageID())
+		}
+	})
+
+	t.Run("retries then dead-letters", func(t *testing.T) {
+		q := newQueue(t)
+		deliveries := consume(t, q, 1)
+
+		publish(t, q, 1)
+		deadLettered, err := receive(t, deliveries).Retry(errors.New("first failure"))
+		if err != nil || deadLettered {
+			t.Fatalf("first Retry = %v, %v", deadLettered, err)
+		}
+		d := receive(t, deliveries)
+		if d.Attempts() != 1 {
+			t.Fatalf("retried delivery has %d attempts, want 1", d.Attempts())
+		}
+		deadLettered, err = d.Retry(errors.New("second failure"))
+		if err != nil || !deadLettered {
+			t.Fatalf("second Retry = %v, %v", deadLettered, err)
+		}
+		expectNone(t, deliveries)
+
+		letters, err := q.ListDeadLetters(0)
+		if err != nil {
+			t.Fatalf("ListDeadLetters: %v", err)
+		}
+		if len(letters) != 1 || letters[0].MessageID != messageID(1) || letters[0].Attempts != 2 ||
+			letters[0].Error != "second failure" || letters[0].Task == nil || letters[0].Task.ProductID != 1 {
+			t.Fatalf("dead letters = %+v", letters)
+		}
+
+		replayed, err := q.ReplayDeadLetters(DeadLetterSelector{ProductID: 1})
+		if err != nil || replayed != 1 {
+			t.Fatalf("ReplayDeadLetters = %d, %v", replayed, err)
+		}
+		d = receive(t, deliveries)
+		if d.MessageID() != messageID(1) || d.Attempts() != 0 {
+			t.Fatalf("replayed delivery = %s (attempts %d)", d.MessageID(), d.Attempts())
+		}
+		d.Ack()
+	})
+
+	t.Run("dead-letters and purges", func(t *testing.T) {
+		q := newQueue(t)
+		deliveries := consume(t, q, 10)
+
+		publish(t, q, 1)
+		publish(t, q, 2)
+		for i := 0; i < 2; i++ {
+			if err := receive(t, deliveries).DeadLetter(errors.New("malformed")); err != nil {
+				t.Fatalf("DeadLetter: %v", err)
+			}
+		}
+
+		if _, err := q.PurgeDeadLetters(DeadLetterSelector{}); !errors.Is(err, ErrEmptySelector) {
+			t.Errorf("empty selector: %v, want %v", err, ErrEmptySelector)
+		}
+		purged, err := q.PurgeDeadLetters(DeadLetterSelector{MessageIDs: []string{messageID(2)}})
+		if err != nil || purged != 1 {
+			t.Fatalf("PurgeDeadLetters = %d, %v", purged, err)
+		}
+		letters, err := q.ListDeadLetters(0)
+		if err != nil || len(letters) != 1 || letters[0].MessageID != messageID(1) || letters[0].Attempts != 1 {
+			t.Fatalf("dead letters = %+v, %v", letters, err)
+		}
+	})
+}
+
+func TestMemoryQueuePublishAfterClose(t *testing.T) {
+	q := NewMemoryQueue(testPolicy)
+	q.Close()
+	if err := q.Publish(context.Background(), []byte(body(1)), messageID(1)); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Publish after Close: %v, want %v", err, ErrQueueClosed)
+	}
+}
+
+func TestPostgresQueueLeaseLost(t *testing.T) {
+	const lease = 200 * time.Millisecond
+	q := newTestPostgresQueue(t, lease)
+	deliveries := consume(t, q, 10)
+
+	publish(t, q, 1)
+	stale := receive(t, deliveries)
+
+	// The lease runs out and the task is claimed again
+	current := receive(t, deliveries)
+	if current.MessageID() != stale.MessageID() {
+		t.Fatalf("redelivery = %s, want %s", current.MessageID(), stale.MessageID())
+	}
+
+	if err := stale.Ack(); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("stale Ack: %v, want %v", err, ErrLeaseLost)
+	}
+	if _, err := stale.Retry(errors.New("late failure")); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("stale Retry: %v, want %v", err, ErrLeaseLost)
+	}
+	if err := current.Ack(); err != nil {
+		t.Errorf("current Ack: %v", err)
+	}
+}
+
+func consume(t *testing.T, q TaskQueue, prefetch int) <-chan Delivery {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	deliveries, err := q.Consume(ctx, prefetch)
+	if err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	return deliveries
+}
+
+func publish(t *testing.T, q TaskQueue, productID int) {
+	t.Helper()
+	if err := q.Publish(context.Background(), []byte(body(productID)), messageID(productID)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+}
+
+func receive(t *testing.T, deliveries <-chan Delivery) Delivery {
+	t.Helper()
+	select {
+	case d, ok := <-deliveries:
+		if !ok {
+			t.Fatal("delivery channel closed")
+		}
+		return d
+	case <-time.After(deliveryTimeout):
+		t.Fatal("timed out waiting for a delivery")
+		return nil
+	}
+}
+
+// expectNone checks nothing is delivered for a while, e.g. before a retry
+// backoff or a lease would have run out
+func expectNone(t *testing.T, deliveries <-chan Delivery) {
+	t.Helper()
+	select {
+	case d := <-deliveries:
+		t.Fatalf("unexpected delivery %s", d.MessageID())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func messageID(productID int) string {
+	return fmt.Sprintf("task-%d", productID)
+}
+
+func body(productID int) string {
+	return fmt.Sprintf(`{"ProductID":%d}`, productID)
+}
@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	ImageProcessingQueue = "image_processing_queue"
+	DeadLetterExchange   = "image_processing_dlx"
+	DeadLetterQueue      = "image_processing_queue.dlq"
+
+	// Headers carried by retried and dead-lettered messages
+	HeaderAttempts  = "x-attempts"
+	HeaderLastError = "x-last-error"
+	HeaderFailedAt  = "x-failed-at"
+)
+
+// RetryPolicy bounds how often and how fast a failed task is retried
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Delay returns the backoff before the given retry (1-based), doubling
+// from BaseDelay and capped at MaxDelay
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// retryQueueName returns the delay queue used for the given retry. The
+// `.retry.N` queues of earlier releases carried their TTL as a queue
+// argument and are left alone.
+func retryQueueName(retry int) string {
+	return fmt.Sprintf("%s.delay.%d", ImageProcessingQueue, retry)
+}
+
+// DeclareTopology declares the work queue, one delay queue per retry that
+// dead-letters expired messages back into the work queue, and the
+// dead-letter exchange and queue for exhausted tasks. Delays are set per
+// message, so the queues' arguments don't depend on the retry policy and
+// changing it doesn't make the declaration fail. It is idempotent, so both
+// the API and the worker call it on startup.
+func DeclareTopology(ch *amqp.Channel, policy RetryPolicy) (amqp.Queue, error) {
+	q, err := ch.QueueDeclare(
+		ImageProcessingQueue, // name
+		true,                 // durable
+		false,                // delete when unused
+		false,                // exclusive
+		false,                // no-wait
+		nil,                  // arguments
+	)
+	if err != nil {
+		return q, fmt.Errorf("failed to declare queue %s: %w", ImageProcessingQueue, err)
+	}
+
+	for retry := 1; retry < policy.MaxAttempts; retry++ {
+		_, err := ch.QueueDeclare(
+			retryQueueName(retry),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": ImageProcessingQueue,
+			},
+		)
+		if err != nil {
+			return q, fmt.Errorf("failed to declare retry queue %d: %w", retry, err)
+		}
+	}
+
+	if err := ch.ExchangeDeclare(
+		DeadLetterExchange, // name
+		"direct",           // type
+		true,               // durable
+		false,              // auto-deleted
+		false,              // internal
+		false,              // no-wait
+		nil,                // arguments
+	); err != nil {
+		return q, fmt.Errorf("failed to declare exchange %s: %w", DeadLetterExchange, err)
+	}
+
+	if _, err := ch.QueueDeclare(DeadLetterQueue, true, false, false, false, nil); err != nil {
+		return q, fmt.Errorf("failed to declare queue %s: %w", DeadLetterQueue, err)
+	}
+
+	if err := ch.QueueBind(DeadLetterQueue, ImageProcessingQueue, DeadLetterExchange, false, nil); err != nil {
+		return q, fmt.Errorf("failed to bind dead-letter queue: %w", err)
+	}
+
+	return q, nil
+}
+
+// Attempts returns how many times the delivery has already been processed
+func Attempts(d amqp.Delivery) int {
+	switch v := d.Headers[HeaderAttempts].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
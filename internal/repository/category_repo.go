@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-management-system/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrParentNotFound   = errors.New("parent category not found")
+	ErrCategoryExists   = errors.New("a category with this slug already exists")
+	ErrCategoryCycle    = errors.New("a category can't be moved under itself or its descendants")
+	ErrCategoryInUse    = errors.New("category still has subcategories or products")
+)
+
+type CategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// Create inserts a category under its parent, if any, and fills in its path
+func (r *CategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkSlugFree(tx, category.Slug, 0); err != nil {
+			return err
+		}
+
+		parentPath := "/"
+		if category.ParentID != nil {
+			parent, err := findParent(tx, *category.ParentID)
+			if err != nil {
+				return err
+			}
+			parentPath = parent.Path
+		}
+
+		// The path includes the category's own ID, which only exists after insert
+		category.Path = parentPath
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		category.Path = fmt.Sprintf("%s%d/", parentPath, category.ID)
+		return tx.Model(category).Update("path", category.Path).Error
+	})
+}
+
+func (r *CategoryRepository) FindByID(ctx context.Context, id uint) (*models.Category, error) {
+	return findCategory(r.db.WithContext(ctx), id)
+}
+
+// List returns every category in tree order, parents before children.
+// A non-nil rootID limits the result to that category's subtree.
+func (r *CategoryRepository) List(ctx context.Context, rootID *uint) ([]models.Category, error) {
+	query := r.db.WithContext(ctx).Model(&models.Category{})
+	if rootID != nil {
+		root, err := r.FindByID(ctx, *rootID)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("path LIKE ?", escapeLike(root.Path)+"%")
+	}
+
+	var categories []models.Category
+	if err := query.Order("path").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// Update saves a category's name and slug and, if its parent changed,
+// moves it together with its whole subtree. The category, its new parent
+// and the parent's ancestors are locked in ID order, so two moves that
+// would together create a cycle run one after the other and the second
+// sees the first's paths.
+func (r *CategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := []uint{category.ID}
+		if category.ParentID != nil {
+			parent, err := findParent(tx, *category.ParentID)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, pathIDs(parent.Path)...)
+		}
+		locked, err := lockCategories(tx, ids)
+		if err != nil {
+			return err
+		}
+
+		existing, ok := locked[category.ID]
+		if !ok {
+			return ErrCategoryNotFound
+		}
+		if err := checkSlugFree(tx, category.Slug, category.ID); err != nil {
+			return err
+		}
+
+		newPath := fmt.Sprintf("/%d/", category.ID)
+		if category.ParentID != nil {
+			// The parent may have been moved or deleted before it was locked
+			parent, ok := locked[*category.ParentID]
+			if !ok {
+				return ErrParentNotFound
+			}
+			if strings.HasPrefix(parent.Path, existing.Path) {
+				return ErrCategoryCycle
+			}
+			newPath = fmt.Sprintf("%s%d/", parent.Path, category.ID)
+		}
+
+		// Rewrite the path prefix of the category and all of its
+		// descendants, including deleted ones, so they stay consistent if
+		// restored
+		if newPath != existing.Path {
+			err := tx.Unscoped().Model(&models.Category{}).
+				Where("path LIKE ?", escapeLike(existing.Path)+"%").
+				Update("path", gorm.Expr("? || substr(path, ?)", newPath, len(existing.Path)+1)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		category.Path = newPath
+		return tx.Model(category).
+			Select("name", "slug", "parent_id", "path").
+			Updates(category).Error
+	})
+}
+
+// Delete soft-deletes a category that has no subcategories or products
+func (r *CategoryRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findCategory(tx, id); err != nil {
+			return err
+		}
+
+		var children, products int64
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Product{}).Where("category_id = ?", id).Count(&products).Error; err != nil {
+			return err
+		}
+		if children > 0 || products > 0 {
+			return ErrCategoryInUse
+		}
+
+		return tx.Delete(&models.Category{}, id).Error
+	})
+}
+
+func findCategory(db *gorm.DB, id uint) (*models.Category, error) {
+	var category models.Category
+	if err := db.First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// lockCategories locks the categories with the given IDs in ID order and
+// returns those that exist
+func lockCategories(db *gorm.DB, ids []uint) (map[uint]models.Category, error) {
+	slices.Sort(ids)
+	var categories []models.Category
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", slices.Compact(ids)).
+		Order("id").
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	locked := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		locked[category.ID] = category
+	}
+	return locked, nil
+}
+
+// pathIDs returns the category IDs in a path such as "/1/4/9/"
+func pathIDs(path string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(part, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+func findParent(db *gorm.DB, id uint) (*models.Category, error) {
+	parent, err := findCategory(db, id)
+	if errors.Is(err, ErrCategoryNotFound) {
+		return nil, ErrParentNotFound
+	}
+	return parent, err
+}
+
+// checkSlugFree fails if a category other than exceptID uses slug
+func checkSlugFree(db *gorm.DB, slug string, exceptID uint) error {
+	var count int64
+	err := db.Unscoped().Model(&models.Category{}).
+		Where("slug = ? AND id <> ?", slug, exceptID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCategoryExists
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"product-management-system/internal/models"
+)
+
+func createCategory(t *testing.T, repo *CategoryRepository, slug string, parentID *uint) *models.Category {
+	t.Helper()
+	category := &models.Category{Name: slug, Slug: slug, ParentID: parentID}
+	if err := repo.Create(context.Background(), category); err != nil {
+		t.Fatalf("Create %s: %v", slug, err)
+	}
+	return category
+}
+
+// move returns a copy of category that moves it under parentID
+func move(category *models.Category, parentID *uint) *models.Category {
+	moved := *category
+	moved.ParentID = parentID
+	return &moved
+}
+
+func TestConcurrentCategoryMovesNeverCycle(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		a := createCategory(t, repo, fmt.Sprintf("a-%d", i), nil)
+		b := createCategory(t, repo, fmt.Sprintf("b-%d", i), nil)
+		// c sits under b, so moving a under c and b under a also cycles
+		c := createCategory(t, repo, fmt.Sprintf("c-%d", i), &b.ID)
+
+		moves := []*models.Category{move(a, &c.ID), move(b, &a.ID)}
+		var wg sync.WaitGroup
+		errs := make(chan error, len(moves))
+		for _, m := range moves {
+			wg.Add(1)
+			go func(m *models.Category) {
+				defer wg.Done()
+				errs <- repo.Update(ctx, m)
+			}(m)
+		}
+		wg.Wait()
+		close(errs)
+
+		moved := 0
+		for err := range errs {
+			switch {
+			case err == nil:
+				moved++
+			case !errors.Is(err, ErrCategoryCycle):
+				t.Fatalf("Update: %v", err)
+			}
+		}
+		if moved != 1 {
+			t.Fatalf("%d of the opposite moves succeeded, want 1", moved)
+		}
+
+		for _, id := range []uint{a.ID, b.ID, c.ID} {
+			category, err := repo.FindByID(ctx, id)
+			if err != nil {
+				t.Fatalf("FindByID: %v", err)
+			}
+			if ids := pathIDs(category.Path); len(ids) > 3 || ids[len(ids)-1] != id {
+				t.Errorf("category %d has path %s", id, category.Path)
+			}
+		}
+	}
+}
+
+func TestCategoryMoveRewritesDeletedDescendants(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	root := createCategory(t, repo, "root", nil)
+	child := createCategory(t, repo, "child", nil)
+	deleted := createCategory(t, repo, "deleted", &child.ID)
+	if err := repo.Delete(ctx, deleted.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if err := repo.Update(ctx, move(child, &root.ID)); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	var stored models.Category
+	if err := db.Unscoped().First(&stored, deleted.ID).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if want := fmt.Sprintf("/%d/%d/%d/", root.ID, child.ID, deleted.ID); stored.Path != want {
+		t.Errorf("deleted descendant path = %s, want %s", stored.Path, want)
+	}
+}
+
+func TestCategoryMoveUnderOwnDescendant(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewCategoryRepository(db)
+
+	parent := createCategory(t, repo, "parent", nil)
+	child := createCategory(t, repo, "child", &parent.ID)
+	if err := repo.Update(context.Background(), move(parent, &child.ID)); !errors.Is(err, ErrCategoryCycle) {
+		t.Errorf("moving under a child: error = %v, want ErrCategoryCycle", err)
+	}
+	if err := repo.Update(context.Background(), move(parent, &parent.ID)); !errors.Is(err, ErrCategoryCycle) {
+		t.Errorf("moving under itself: error = %v, want ErrCategoryCycle", err)
+	}
+}
+
+func TestPathIDs(t *testing.T) {
+	tests := []struct {
+		path string
+		want []uint
+	}{
+		{"/1/", []uint{1}},
+		{"/1/4/9/", []uint{1, 4, 9}},
+		{"/", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := pathIDs(tt.path)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("pathIDs(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"product-management-system/internal/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Claim leases up to limit unsent messages that are due at now by moving
+// their AvailableAt to now+lease, and returns them oldest first. Other
+// relays skip claimed messages until the lease runs out, so a relay that
+// dies mid-batch only delays its messages.
+func (r *OutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE outbox_messages SET available_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE sent_at IS NULL AND available_at <= ?
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), now, limit).
+		Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING doesn't preserve the subquery's order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+// MarkSent records that a message was published
+func (r *OutboxRepository) MarkSent(ctx context.Context, id uint, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"sent_at":    now,
+			"last_error": "",
+		}).Error
+}
+
+// MarkFailed records a failed publish and makes the message available
+// again at retryAt
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uint, cause error, retryAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   cause.Error(),
+			"available_at": retryAt,
+		}).Error
+}
+
+// PurgeSent deletes up to limit messages sent before the given time and
+// returns how many were deleted
+func (r *OutboxRepository) PurgeSent(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		DELETE FROM outbox_messages
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE sent_at < ?
+			ORDER BY id
+			LIMIT ?
+		)`, before, limit)
+	return result.RowsAffected, result.Error
+}
+
+// enqueueOutbox writes a message to the outbox in tx, so it is published
+// only if tx commits
+func enqueueOutbox(tx *gorm.DB, topic string, payload interface{}, now time.Time) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %w", topic, err)
+	}
+	return tx.Create(&models.OutboxMessage{
+		Topic:       topic,
+		Payload:     body,
+		AvailableAt: now,
+	}).Error
+}
+
+// enqueueImageProcessing queues processing of a product's images in tx
+func enqueueImageProcessing(tx *gorm.DB, task *models.ImageProcessingTask, now time.Time) error {
+	return enqueueOutbox(tx, models.OutboxTopicImageProcessing, task, now)
+}
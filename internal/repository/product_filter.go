@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"product-management-system/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sort keys accepted by ProductFilter.Sort
+const (
+	SortCreatedAt = "created_at"
+	SortPrice     = "price"
+	SortName      = "name"
+)
+
+// Page size bounds for product listings
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortColumns maps public sort keys to database columns. Price sorts use
+// the filter's PriceScale instead.
+var sortColumns = map[string]string{
+	SortCreatedAt: "created_at",
+	SortPrice:     "price_amount",
+	SortName:      "product_name",
+}
+
+// ProductFilter describes a product listing query
+type ProductFilter struct {
+	UserID uint
+	// Currency is the requested listing currency, empty for the default.
+	// Prices filters the price range and sorts in that currency.
+	Currency    string
+	Prices      PriceScale
+	MinPrice    *float64
+	MaxPrice    *float64
+	ProductName string
+	Visibility  string
+	// CategoryID matches the category and all of its descendants
+	CategoryID *uint
+	// Tags must all be present on a product
+	Tags []string
+	// Attributes maps attribute keys to the required value, as text
+	Attributes map[string]string
+
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+	Cursor     *ProductCursor
+}
+
+// ProductPage is a single page of a product listing
+type ProductPage struct {
+	Products   []models.Product
+	Total      int64
+	NextCursor string
+}
+
+// ProductCursor marks the position after the last product of a page. It is
+// tied to the sort and currency it was produced with and encoded opaquely
+// for clients. Price cursors carry no value: converted prices are looked
+// up from the product when the next page is fetched.
+type ProductCursor struct {
+	Sort       string          `json:"s"`
+	Descending bool            `json:"d"`
+	Currency   string          `json:"c,omitempty"`
+	Value      json.RawMessage `json:"v"`
+	ID         uint            `json:"id"`
+}
+
+// EncodeCursor builds the cursor pointing after product for the given sort
+func EncodeCursor(product *models.Product, sort string, descending bool, currency string) (string, error) {
+	var value interface{}
+	switch sort {
+	case SortPrice:
+		value = nil
+	case SortName:
+		value = product.ProductName
+	default:
+		value = product.CreatedAt
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(ProductCursor{Sort: sort, Descending: descending, Currency: currency, Value: raw, ID: product.ID})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor parses an opaque cursor string
+func DecodeCursor(encoded string) (*ProductCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor ProductCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := sortColumns[cursor.Sort]; !ok || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// value decodes the cursor's sort value into the type of its column
+func (c *ProductCursor) value() (interface{}, error) {
+	var err error
+	switch c.Sort {
+	case SortName:
+		var name string
+		err = json.Unmarshal(c.Value, &name)
+		return name, err
+	default:
+		var createdAt time.Time
+		err = json.Unmarshal(c.Value, &createdAt)
+		return createdAt, err
+	}
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (f *ProductFilter) sortColumn() string {
+	if f.Sort == SortPrice {
+		return f.Prices.expr()
+	}
+	if column, ok := sortColumns[f.Sort]; ok {
+		return column
+	}
+	return sortColumns[SortCreatedAt]
+}
+
+func (f *ProductFilter) orderClause() string {
+	direction := "ASC"
+	if f.Descending {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", f.sortColumn(), direction, direction)
+}
+
+// attributeCandidates returns the JSON documents an attribute filter
+// matches. Query values arrive as text, so "42" matches both the string
+// "42" and the number 42, and "true" matches the boolean as well.
+func attributeCandidates(key, value string) []string {
+	values := []interface{}{value}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		values = append(values, number)
+	}
+	if value == "true" || value == "false" {
+		values = append(values, value == "true")
+	}
+
+	candidates := make([]string, 0, len(values))
+	for _, v := range values {
+		doc, err := json.Marshal(map[string]interface{}{key: v})
+		if err == nil {
+			candidates = append(candidates, string(doc))
+		}
+	}
+	return candidates
+}
+
+// decimalArg formats a price bound for comparison with numeric prices
+// without float rounding in SQL
+func decimalArg(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
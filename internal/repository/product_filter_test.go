@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 30, 45, 123456000, time.UTC)
+	product := &models.Product{ProductName: "Desk lamp"}
+	product.ID = 42
+	product.CreatedAt = createdAt
+
+	tests := []struct {
+		sort       string
+		descending bool
+		currency   string
+		value      interface{}
+	}{
+		{SortCreatedAt, false, "", createdAt},
+		{SortCreatedAt, true, "", createdAt},
+		{SortName, false, "", "Desk lamp"},
+		{SortName, true, "EUR", "Desk lamp"},
+		{SortPrice, true, "JPY", nil},
+	}
+	for _, tt := range tests {
+		encoded, err := EncodeCursor(product, tt.sort, tt.descending, tt.currency)
+		if err != nil {
+			t.Fatalf("EncodeCursor(%s): %v", tt.sort, err)
+		}
+		cursor, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%s): %v", tt.sort, err)
+		}
+		if cursor.Sort != tt.sort || cursor.Descending != tt.descending || cursor.Currency != tt.currency || cursor.ID != 42 {
+			t.Errorf("cursor = %+v, want sort %s descending %v currency %q", cursor, tt.sort, tt.descending, tt.currency)
+		}
+		if tt.value == nil {
+			continue
+		}
+
+		value, err := cursor.value()
+		if err != nil {
+			t.Fatalf("value(%s): %v", tt.sort, err)
+		}
+		if createdAt, ok := tt.value.(time.Time); ok {
+			if got, ok := value.(time.Time); !ok || !got.Equal(createdAt) {
+				t.Errorf("value(%s) = %v, want %v", tt.sort, value, createdAt)
+			}
+		} else if value != tt.value {
+			t.Errorf("value(%s) = %v, want %v", tt.sort, value, tt.value)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"not base64", "!!!"},
+		{"not json", encode("cursor")},
+		{"unknown sort", encode(`{"s":"stock","v":1,"id":1}`)},
+		{"column name as sort", encode(`{"s":"price_amount","v":1,"id":1}`)},
+		{"missing id", encode(`{"s":"name","v":"a"}`)},
+		{"zero id", encode(`{"s":"name","v":"a","id":0}`)},
+		{"negative id", encode(`{"s":"name","v":"a","id":-1}`)},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeCursor(tt.encoded); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: error = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
+
+func TestCursorValueRejectsWrongType(t *testing.T) {
+	tests := []*ProductCursor{
+		{Sort: SortName, Value: []byte(`42`), ID: 1},
+		{Sort: SortCreatedAt, Value: []byte(`"yesterday"`), ID: 1},
+	}
+	for _, cursor := range tests {
+		if _, err := cursor.value(); err == nil {
+			t.Errorf("value(%s, %s) succeeded", cursor.Sort, cursor.Value)
+		}
+	}
+}
+
+func TestListPagesWithCursor(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	// Duplicate names and prices make the id tie-breaker matter
+	for i := 0; i < 7; i++ {
+		product := &models.Product{
+			UserID:      1,
+			ProductName: fmt.Sprintf("Lamp %d", i%3),
+			Price:       money.Money{Amount: int64(1000 + 100*(i%2)), Currency: "USD"},
+			Visibility:  models.VisibilityPublic,
+		}
+		if err := repo.Create(ctx, product); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	for _, sort := range []string{SortCreatedAt, SortName, SortPrice} {
+		for _, descending := range []bool{false, true} {
+			seen := map[uint]bool{}
+			filter := ProductFilter{Sort: sort, Descending: descending, Limit: 3}
+			for pages := 0; ; pages++ {
+				if pages > 3 {
+					t.Fatalf("%s descending=%v: too many pages", sort, descending)
+				}
+				page, err := repo.List(ctx, filter)
+				if err != nil {
+					t.Fatalf("%s descending=%v: List: %v", sort, descending, err)
+				}
+				for _, product := range page.Products {
+					if seen[product.ID] {
+						t.Fatalf("%s descending=%v: product %d listed twice", sort, descending, product.ID)
+					}
+					seen[product.ID] = true
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				if filter.Cursor, err = DecodeCursor(page.NextCursor); err != nil {
+					t.Fatalf("DecodeCursor: %v", err)
+				}
+			}
+			if len(seen) != 7 {
+				t.Errorf("%s descending=%v: listed %d products, want 7", sort, descending, len(seen))
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"product-management-system/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientStock    = errors.New("not enough stock available")
+	ErrStockBelowReserved   = errors.New("stock can't be set below the reserved quantity")
+	ErrVariantRequired      = errors.New("product has variants, so a variant must be reserved")
+	ErrReservationNotFound  = errors.New("reservation not found")
+	ErrReservationNotActive = errors.New("reservation was already committed, released or expired")
+	ErrReservationExpired   = errors.New("reservation has expired")
+)
+
+// Reserve holds stock for a new reservation. Stock is checked and claimed
+// by a single conditional update, so concurrent reservations can't take
+// more than is available.
+func (r *ProductRepository) Reserve(ctx context.Context, reservation *models.StockReservation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if reservation.VariantID == nil {
+			var variants int64
+			err := tx.Model(&models.ProductVariant{}).
+				Where("product_id = ?", reservation.ProductID).
+				Count(&variants).Error
+			if err != nil {
+				return err
+			}
+			if variants > 0 {
+				return ErrVariantRequired
+			}
+		}
+
+		result := stockRow(tx, reservation.ProductID, reservation.VariantID).
+			Where("stock - reserved >= ?", reservation.Quantity).
+			UpdateColumn("reserved", gorm.Expr("reserved + ?", reservation.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return stockUnavailable(tx, reservation)
+		}
+
+		reservation.Status = models.ReservationActive
+		return tx.Create(reservation).Error
+	})
+}
+
+func (r *ProductRepository) FindReservation(ctx context.Context, id uint) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	if err := r.db.WithContext(ctx).First(&reservation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReservationNotFound
+		}
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// CommitReservation takes an active, unexpired reservation's units out of
+// stock
+func (r *ProductRepository) CommitReservation(ctx context.Context, id uint, now time.Time) (*models.StockReservation, error) {
+	return r.finishReservation(ctx, id, func(reservation *models.StockReservation) (string, error) {
+		if !now.Before(reservation.ExpiresAt) {
+			return "", ErrReservationExpired
+		}
+		return models.ReservationCommitted, nil
+	})
+}
+
+// ReleaseReservation returns an active reservation's units to stock
+func (r *ProductRepository) ReleaseReservation(ctx context.Context, id uint) (*models.StockReservation, error) {
+	return r.finishReservation(ctx, id, func(*models.StockReservation) (string, error) {
+		return models.ReservationReleased, nil
+	})
+}
+
+// ExpireReservations releases up to limit active reservations that expired
+// by now and returns how many it released. Reservations locked by another
+// transaction are skipped, so several instances can run this at once.
+func (r *ProductRepository) ExpireReservations(ctx context.Context, now time.Time, limit int) (int, error) {
+	var expired int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reservations []models.StockReservation
+		// Ordering by stock row keeps concurrent sweeps from deadlocking
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at <= ?", models.ReservationActive, now).
+			Order("product_id, variant_id NULLS FIRST, id").
+			Limit(limit).
+			Find(&reservations).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range reservations {
+			if err := settleReservation(tx, &reservations[i], models.ReservationExpired); err != nil {
+				return err
+			}
+		}
+		expired = len(reservations)
+		return nil
+	})
+	return expired, err
+}
+
+// finishReservation locks an active reservation and moves it to the status
+// chosen by next, adjusting stock to match
+func (r *ProductRepository) finishReservation(ctx context.Context, id uint, next func(*models.StockReservation) (string, error)) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if reservation.Status != models.ReservationActive {
+			return ErrReservationNotActive
+		}
+
+		status, err := next(&reservation)
+		if err != nil {
+			return err
+		}
+		return settleReservation(tx, &reservation, status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// settleReservation drops a reservation's hold on stock, taking the units
+// out of stock as well if it was committed, and records its new status
+func settleReservation(tx *gorm.DB, reservation *models.StockReservation, status string) error {
+	updates := map[string]interface{}{
+		"reserved": gorm.Expr("reserved - ?", reservation.Quantity),
+	}
+	if status == models.ReservationCommitted {
+		updates["stock"] = gorm.Expr("stock - ?", reservation.Quantity)
+	}
+
+	// Unscoped so stock stays consistent if the product was deleted since
+	err := stockRow(tx.Unscoped(), reservation.ProductID, reservation.VariantID).
+		UpdateColumns(updates).Error
+	if err != nil {
+		return err
+	}
+
+	reservation.Status = status
+	return tx.Model(reservation).Update("status", status).Error
+}
+
+// stockRow scopes a query to the product or variant row holding the stock
+func stockRow(db *gorm.DB, productID uint, variantID *uint) *gorm.DB {
+	if variantID != nil {
+		return db.Model(&models.ProductVariant{}).
+			Where("id = ? AND product_id = ?", *variantID, productID)
+	}
+	return db.Model(&models.Product{}).Where("id = ?", productID)
+}
+
+// stockUnavailable explains why a reservation's conditional update matched
+// no row
+func stockUnavailable(tx *gorm.DB, reservation *models.StockReservation) error {
+	var count int64
+	if err := stockRow(tx, reservation.ProductID, reservation.VariantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrInsufficientStock
+	}
+	if reservation.VariantID != nil {
+		return ErrVariantNotFound
+	}
+	return ErrProductNotFound
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+)
+
+func TestReserveNeverOversells(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	const stock = 10
+	product := &models.Product{
+		UserID:      1,
+		ProductName: "Mug",
+		Price:       money.Money{Amount: 500, Currency: "USD"},
+		Stock:       stock,
+	}
+	if err := repo.Create(ctx, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Twice as many buyers as units, all at once
+	const buyers = 2 * stock
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	start := make(chan struct{})
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			<-start
+			errs <- repo.Reserve(ctx, &models.StockReservation{
+				UserID:    userID,
+				ProductID: product.ID,
+				Quantity:  1,
+				ExpiresAt: time.Now().Add(time.Minute),
+			})
+		}(uint(i + 2))
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	reserved, rejected := 0, 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			reserved++
+		case errors.Is(err, ErrInsufficientStock):
+			rejected++
+		default:
+			t.Fatalf("Reserve: %v", err)
+		}
+	}
+	if reserved != stock || rejected != buyers-stock {
+		t.Errorf("%d reserved and %d rejected, want %d and %d", reserved, rejected, stock, buyers-stock)
+	}
+
+	stored, err := repo.FindByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.Reserved != stock || stored.Stock != stock {
+		t.Errorf("stock %d, reserved %d; want %d, %d", stored.Stock, stored.Reserved, stock, stock)
+	}
+}
+
+func TestReservationLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{
+		UserID:      1,
+		ProductName: "Mug",
+		Price:       money.Money{Amount: 500, Currency: "USD"},
+		Stock:       5,
+	}
+	if err := repo.Create(ctx, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	now := time.Now()
+	reserve := func(quantity int, expiresAt time.Time) *models.StockReservation {
+		t.Helper()
+		reservation := &models.StockReservation{UserID: 2, ProductID: product.ID, Quantity: quantity, ExpiresAt: expiresAt}
+		if err := repo.Reserve(ctx, reservation); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		return reservation
+	}
+	committed := reserve(2, now.Add(time.Hour))
+	released := reserve(1, now.Add(time.Hour))
+	expired := reserve(1, now.Add(-time.Second))
+
+	if _, err := repo.CommitReservation(ctx, committed.ID, now); err != nil {
+		t.Fatalf("CommitReservation: %v", err)
+	}
+	if _, err := repo.CommitReservation(ctx, committed.ID, now); !errors.Is(err, ErrReservationNotActive) {
+		t.Errorf("second commit: %v, want %v", err, ErrReservationNotActive)
+	}
+	if _, err := repo.ReleaseReservation(ctx, released.ID); err != nil {
+		t.Fatalf("ReleaseReservation: %v", err)
+	}
+	if _, err := repo.CommitReservation(ctx, expired.ID, now); !errors.Is(err, ErrReservationExpired) {
+		t.Errorf("commit after expiry: %v, want %v", err, ErrReservationExpired)
+	}
+	if n, err := repo.ExpireReservations(ctx, now, 10); err != nil || n != 1 {
+		t.Errorf("ExpireReservations = %d, %v; want 1", n, err)
+	}
+
+	stored, err := repo.FindByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.Stock != 3 || stored.Reserved != 0 {
+		t.Errorf("stock %d, reserved %d; want 3, 0", stored.Stock, stored.Reserved)
+	}
+}
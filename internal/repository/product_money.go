@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// priceFactorDigits is the number of decimals conversion factors are
+// written with in SQL, far below the smallest minor unit
+const priceFactorDigits = 12
+
+var ErrPriceListEntryNotFound = errors.New("product has no price in this currency")
+
+// PriceScale converts stored prices to major units of one currency in SQL,
+// so products priced in different currencies can be filtered, sorted and
+// bucketed together. A product's price list entry in Currency is used
+// instead of converting its price. The zero PriceScale compares raw minor
+// units.
+type PriceScale struct {
+	Currency string
+	// Factors turn minor units of each stored currency into major units
+	// of Currency
+	Factors map[string]*big.Rat
+}
+
+// expr returns the SQL expression for a product's price on the scale.
+// Currency codes are validated ISO codes, so they can be inlined.
+func (p PriceScale) expr() string {
+	if p.Currency == "" {
+		return "products.price_amount"
+	}
+
+	currencies := make([]string, 0, len(p.Factors))
+	for currency := range p.Factors {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var b strings.Builder
+	b.WriteString("coalesce((SELECT e.amount * ")
+	b.WriteString(p.factor(p.Currency))
+	b.WriteString(" FROM price_list_entries e WHERE e.product_id = products.id AND e.currency = '")
+	b.WriteString(p.Currency)
+	b.WriteString("'), products.price_amount * CASE products.price_currency")
+	for _, currency := range currencies {
+		b.WriteString(" WHEN '" + currency + "' THEN " + p.factor(currency))
+	}
+	b.WriteString(" END)")
+	return b.String()
+}
+
+func (p PriceScale) factor(currency string) string {
+	factor, ok := p.Factors[currency]
+	if !ok {
+		return "NULL"
+	}
+	return factor.FloatString(priceFactorDigits)
+}
+
+// ListPriceList returns a product's price list entries by currency
+func (r *ProductRepository) ListPriceList(ctx context.Context, productID uint) ([]models.PriceListEntry, error) {
+	var entries []models.PriceListEntry
+	err := r.db.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("currency").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// PriceListAmounts returns the price list amounts in currency of the given
+// products, keyed by product ID
+func (r *ProductRepository) PriceListAmounts(ctx context.Context, productIDs []uint, currency string) (map[uint]int64, error) {
+	amounts := make(map[uint]int64)
+	if len(productIDs) == 0 {
+		return amounts, nil
+	}
+
+	var entries []models.PriceListEntry
+	err := r.db.WithContext(ctx).
+		Where("product_id IN ? AND currency = ?", productIDs, currency).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		amounts[entry.ProductID] = entry.Amount
+	}
+	return amounts, nil
+}
+
+// SetPriceListEntry creates or replaces a product's price in one currency
+func (r *ProductRepository) SetPriceListEntry(ctx context.Context, entry *models.PriceListEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, entry.ProductID); err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "product_id"}, {Name: "currency"}},
+			DoUpdates: clause.AssignmentColumns([]string{"amount", "updated_at"}),
+		}).Create(entry).Error
+	})
+}
+
+func (r *ProductRepository) DeletePriceListEntry(ctx context.Context, productID uint, currency string) error {
+	result := r.db.WithContext(ctx).
+		Where("product_id = ? AND currency = ?", productID, currency).
+		Delete(&models.PriceListEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPriceListEntryNotFound
+	}
+	return nil
+}
+
+// MigrateMoney moves prices from the decimal columns used before prices
+// had a currency into integer minor units of currency, then drops the old
+// columns. It does nothing once they are gone.
+func MigrateMoney(db *gorm.DB, currency string) error {
+	exponent, err := money.Exponent(currency)
+	if err != nil {
+		return err
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil).String()
+	args := []interface{}{sql.Named("scale", scale), sql.Named("currency", currency)}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		steps := []struct {
+			table  string
+			column string
+			sql    []string
+		}{
+			{"products", "product_price", []string{
+				`UPDATE products SET price_amount = coalesce(round(product_price * @scale::numeric), 0), price_currency = @currency`,
+				`ALTER TABLE products DROP COLUMN product_price, DROP COLUMN IF EXISTS price_min, DROP COLUMN IF EXISTS price_max`,
+			}},
+			{"product_variants", "price", []string{
+				`UPDATE product_variants SET price_amount = round(price * @scale::numeric) WHERE price IS NOT NULL`,
+				`ALTER TABLE product_variants DROP COLUMN price`,
+			}},
+			{"price_changes", "price", []string{
+				`UPDATE price_changes SET price_amount = round(price * @scale::numeric), price_currency = @currency`,
+				`ALTER TABLE price_changes DROP COLUMN price`,
+			}},
+			{"price_schedules", "price", []string{
+				`UPDATE price_schedules SET price_amount = round(price * @scale::numeric), price_currency = @currency,
+					base_amount = round(base_price * @scale::numeric)`,
+				`ALTER TABLE price_schedules DROP COLUMN price, DROP COLUMN IF EXISTS base_price`,
+			}},
+		}
+
+		migrated := false
+		for _, step := range steps {
+			if !migrator.HasColumn(step.table, step.column) {
+				continue
+			}
+			for _, statement := range step.sql {
+				if err := tx.Exec(statement, args...).Error; err != nil {
+					return err
+				}
+			}
+			migrated = true
+		}
+		if !migrated {
+			return nil
+		}
+		return updatePriceRanges(tx, "")
+	})
+}
+
+// NewPriceScale returns the scale that converts stored prices in any
+// currency with a rate into currency
+func NewPriceScale(rates *money.Rates, currency string) (PriceScale, error) {
+	scale := PriceScale{Currency: currency, Factors: make(map[string]*big.Rat)}
+	for _, from := range rates.Currencies() {
+		factor, err := rates.Factor(from, currency)
+		if err != nil {
+			return PriceScale{}, err
+		}
+		scale.Factors[from] = factor
+	}
+	return scale, nil
+}
+
+// recordPriceChange appends price to the product's price history
+func recordPriceChange(tx *gorm.DB, productID uint, price money.Money, source string, scheduleID *uint, now time.Time) error {
+	return tx.Create(&models.PriceChange{
+		ProductID:  productID,
+		Price:      price,
+		Source:     source,
+		ChangedAt:  now,
+		ScheduleID: scheduleID,
+	}).Error
+}
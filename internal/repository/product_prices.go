@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPriceScheduleNotFound = errors.New("price schedule not found")
+	ErrPriceScheduleOverlap  = errors.New("price schedule overlaps another scheduled or active price")
+	ErrPriceScheduleFinished = errors.New("price schedule has already ended or been cancelled")
+	ErrCurrencyMismatch      = errors.New("price must be in the product's currency")
+)
+
+// ListPriceChanges returns a product's price history, oldest first
+func (r *ProductRepository) ListPriceChanges(ctx context.Context, productID uint) ([]models.PriceChange, error) {
+	var changes []models.PriceChange
+	err := r.db.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("changed_at, id").
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ListPriceSchedules returns every schedule of a product by start time
+func (r *ProductRepository) ListPriceSchedules(ctx context.Context, productID uint) ([]models.PriceSchedule, error) {
+	var schedules []models.PriceSchedule
+	err := r.db.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("starts_at, id").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// CreatePriceSchedule adds a schedule that doesn't overlap the product's
+// pending or active schedules. A schedule without an end occupies only
+// its start time.
+func (r *ProductRepository) CreatePriceSchedule(ctx context.Context, schedule *models.PriceSchedule) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := lockProductPrice(tx, schedule.ProductID)
+		if err != nil {
+			return err
+		}
+		if current.Currency != schedule.Price.Currency {
+			return ErrCurrencyMismatch
+		}
+
+		end := schedule.StartsAt.Add(time.Microsecond)
+		if schedule.EndsAt != nil {
+			end = *schedule.EndsAt
+		}
+		var overlapping int64
+		err = tx.Model(&models.PriceSchedule{}).
+			Where("product_id = ? AND status IN ?", schedule.ProductID,
+				[]string{models.PriceScheduleScheduled, models.PriceScheduleActive}).
+			Where("starts_at < ? AND coalesce(ends_at, starts_at + interval '1 microsecond') > ?", end, schedule.StartsAt).
+			Count(&overlapping).Error
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrPriceScheduleOverlap
+		}
+
+		schedule.Status = models.PriceScheduleScheduled
+		return tx.Create(schedule).Error
+	})
+}
+
+// CancelPriceSchedule cancels a pending schedule, or ends an active one
+// early and restores the product's base price
+func (r *ProductRepository) CancelPriceSchedule(ctx context.Context, productID, scheduleID uint, now time.Time) (*models.PriceSchedule, error) {
+	var schedule models.PriceSchedule
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the schedule before the product, in the same order as
+		// ApplyPriceSchedules
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", productID).
+			First(&schedule, scheduleID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPriceScheduleNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		switch schedule.Status {
+		case models.PriceScheduleScheduled:
+		case models.PriceScheduleActive:
+			if err := endPriceSchedule(tx, &schedule, now); err != nil {
+				return err
+			}
+		default:
+			return ErrPriceScheduleFinished
+		}
+
+		schedule.Status = models.PriceScheduleCancelled
+		return tx.Model(&schedule).Update("status", schedule.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ApplyPriceSchedules starts and ends up to limit schedules that are due
+// by now. It returns how many it handled and the IDs of products whose
+// price changed. Schedules locked by another transaction are skipped, so
+// several instances can run this at once.
+func (r *ProductRepository) ApplyPriceSchedules(ctx context.Context, now time.Time, limit int) (int, []uint, error) {
+	var handled int
+	var changed []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var schedules []models.PriceSchedule
+		// Within a product, events run in time order, so a sale ending at
+		// the moment the next one starts is ended first
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND starts_at <= ?) OR (status = ? AND ends_at <= ?)",
+				models.PriceScheduleScheduled, now, models.PriceScheduleActive, now).
+			Order("product_id, CASE WHEN status = '" + models.PriceScheduleActive + "' THEN ends_at ELSE starts_at END, id").
+			Limit(limit).
+			Find(&schedules).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range schedules {
+			schedule := &schedules[i]
+			var err error
+			if schedule.Status == models.PriceScheduleActive {
+				err = endPriceSchedule(tx, schedule, now)
+			} else {
+				err = startPriceSchedule(tx, schedule, now)
+			}
+			if errors.Is(err, ErrProductNotFound) {
+				// The product was deleted; drop its schedule
+				schedule.Status = models.PriceScheduleCancelled
+			} else if err != nil {
+				return err
+			} else if len(changed) == 0 || changed[len(changed)-1] != schedule.ProductID {
+				changed = append(changed, schedule.ProductID)
+			}
+
+			if err := tx.Model(schedule).Updates(map[string]interface{}{
+				"status":      schedule.Status,
+				"base_amount": schedule.BaseAmount,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		handled = len(schedules)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return handled, changed, nil
+}
+
+// startPriceSchedule sets the product to the schedule's price and records
+// the price it replaced. Schedules whose whole window passed before they
+// could start end without changing the price, and schedules in a currency
+// the product no longer uses are cancelled.
+func startPriceSchedule(tx *gorm.DB, schedule *models.PriceSchedule, now time.Time) error {
+	current, err := lockProductPrice(tx, schedule.ProductID)
+	if err != nil {
+		return err
+	}
+
+	if schedule.EndsAt != nil && !now.Before(*schedule.EndsAt) {
+		schedule.Status = models.PriceScheduleEnded
+		return nil
+	}
+	if current.Currency != schedule.Price.Currency {
+		schedule.Status = models.PriceScheduleCancelled
+		return nil
+	}
+
+	schedule.BaseAmount = &current.Amount
+	schedule.Status = models.PriceScheduleActive
+	if schedule.EndsAt == nil {
+		schedule.Status = models.PriceScheduleEnded
+	}
+	return setProductPrice(tx, schedule.ProductID, schedule.Price, current, models.PriceSourceScheduleStart, &schedule.ID, now)
+}
+
+// endPriceSchedule restores the product's base price, unless the price was
+// changed by hand while the schedule was active
+func endPriceSchedule(tx *gorm.DB, schedule *models.PriceSchedule, now time.Time) error {
+	current, err := lockProductPrice(tx, schedule.ProductID)
+	if err != nil {
+		return err
+	}
+
+	schedule.Status = models.PriceScheduleEnded
+	if schedule.BaseAmount == nil || current != schedule.Price {
+		return nil
+	}
+	base := money.Money{Amount: *schedule.BaseAmount, Currency: schedule.Price.Currency}
+	return setProductPrice(tx, schedule.ProductID, base, current, models.PriceSourceScheduleEnd, &schedule.ID, now)
+}
+
+// lockProductPrice locks a product and returns its current price
+func lockProductPrice(tx *gorm.DB, productID uint) (money.Money, error) {
+	var product models.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "price_amount", "price_currency").
+		First(&product, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return money.Money{}, ErrProductNotFound
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+	return product.Price, nil
+}
+
+// setProductPrice changes a locked product's price, records the change and
+// updates the price range of variants that inherit it
+func setProductPrice(tx *gorm.DB, productID uint, price, current money.Money, source string, scheduleID *uint, now time.Time) error {
+	if price == current {
+		return nil
+	}
+
+	err := tx.Model(&models.Product{}).
+		Where("id = ?", productID).
+		Updates(map[string]interface{}{
+			"price_amount":   price.Amount,
+			"price_currency": price.Currency,
+		}).Error
+	if err != nil {
+		return err
+	}
+	if err := recordPriceChange(tx, productID, price, source, scheduleID, now); err != nil {
+		return err
+	}
+	return refreshPriceRange(tx, productID)
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+)
+
+// priceTest holds a product priced at 1000 USD and helpers to schedule
+// and check its price
+type priceTest struct {
+	t       *testing.T
+	repo    *ProductRepository
+	product *models.Product
+	start   time.Time
+}
+
+func newPriceTest(t *testing.T) *priceTest {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	product := &models.Product{UserID: 1, ProductName: "Kettle", Price: money.Money{Amount: 1000, Currency: "USD"}, Stock: 1}
+	if err := repo.Create(context.Background(), product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return &priceTest{t: t, repo: repo, product: product, start: time.Now().Add(time.Hour).Truncate(time.Second)}
+}
+
+// at returns the time offset from the test's start
+func (p *priceTest) at(offset time.Duration) time.Time {
+	return p.start.Add(offset)
+}
+
+func (p *priceTest) schedule(amount int64, from, to time.Duration) *models.PriceSchedule {
+	p.t.Helper()
+	ends := p.at(to)
+	schedule := &models.PriceSchedule{
+		ProductID: p.product.ID,
+		Price:     money.Money{Amount: amount, Currency: "USD"},
+		StartsAt:  p.at(from),
+		EndsAt:    &ends,
+	}
+	if err := p.repo.CreatePriceSchedule(context.Background(), schedule); err != nil {
+		p.t.Fatalf("CreatePriceSchedule: %v", err)
+	}
+	return schedule
+}
+
+func (p *priceTest) apply(offset time.Duration, wantHandled int) {
+	p.t.Helper()
+	handled, _, err := p.repo.ApplyPriceSchedules(context.Background(), p.at(offset), 100)
+	if err != nil {
+		p.t.Fatalf("ApplyPriceSchedules: %v", err)
+	}
+	if handled != wantHandled {
+		p.t.Errorf("ApplyPriceSchedules at +%v handled %d schedules, want %d", offset, handled, wantHandled)
+	}
+}
+
+func (p *priceTest) assertPrice(want money.Money) {
+	p.t.Helper()
+	stored, err := p.repo.FindByID(context.Background(), p.product.ID)
+	if err != nil {
+		p.t.Fatalf("FindByID: %v", err)
+	}
+	if stored.Price != want {
+		p.t.Errorf("price = %v, want %v", stored.Price, want)
+	}
+}
+
+func (p *priceTest) assertStatus(schedule *models.PriceSchedule, want string) *models.PriceSchedule {
+	p.t.Helper()
+	schedules, err := p.repo.ListPriceSchedules(context.Background(), p.product.ID)
+	if err != nil {
+		p.t.Fatalf("ListPriceSchedules: %v", err)
+	}
+	for i := range schedules {
+		if schedules[i].ID == schedule.ID {
+			if schedules[i].Status != want {
+				p.t.Errorf("schedule %d status = %s, want %s", schedule.ID, schedules[i].Status, want)
+			}
+			return &schedules[i]
+		}
+	}
+	p.t.Fatalf("schedule %d not found", schedule.ID)
+	return nil
+}
+
+func usd(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "USD"}
+}
+
+func TestPriceScheduleRestoresBasePrice(t *testing.T) {
+	p := newPriceTest(t)
+	sale := p.schedule(800, 0, time.Hour)
+
+	p.apply(-time.Minute, 0)
+	p.assertPrice(usd(1000))
+
+	p.apply(0, 1)
+	p.assertPrice(usd(800))
+	if active := p.assertStatus(sale, models.PriceScheduleActive); active.BaseAmount == nil || *active.BaseAmount != 1000 {
+		t.Errorf("BaseAmount = %v, want 1000", active.BaseAmount)
+	}
+
+	p.apply(time.Hour, 1)
+	p.assertPrice(usd(1000))
+	p.assertStatus(sale, models.PriceScheduleEnded)
+
+	changes, err := p.repo.ListPriceChanges(context.Background(), p.product.ID)
+	if err != nil {
+		t.Fatalf("ListPriceChanges: %v", err)
+	}
+	var sources []string
+	for _, change := range changes {
+		sources = append(sources, change.Source)
+	}
+	want := []string{models.PriceSourceManual, models.PriceSourceScheduleStart, models.PriceSourceScheduleEnd}
+	if len(sources) != len(want) || sources[0] != want[0] || sources[1] != want[1] || sources[2] != want[2] {
+		t.Errorf("price change sources = %v, want %v", sources, want)
+	}
+}
+
+func TestPriceScheduleKeepsManualEdit(t *testing.T) {
+	p := newPriceTest(t)
+	sale := p.schedule(800, 0, time.Hour)
+	p.apply(0, 1)
+
+	p.product.Price = usd(900)
+	if err := p.repo.Update(context.Background(), p.product, false); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	p.apply(time.Hour, 1)
+	p.assertPrice(usd(900))
+	p.assertStatus(sale, models.PriceScheduleEnded)
+}
+
+func TestBackToBackPriceSchedules(t *testing.T) {
+	p := newPriceTest(t)
+	first := p.schedule(800, 0, time.Hour)
+	second := p.schedule(700, time.Hour, 2*time.Hour)
+
+	p.apply(0, 1)
+	p.assertPrice(usd(800))
+
+	// The first sale ends before the second starts, so the second
+	// remembers the base price rather than the first sale's
+	p.apply(time.Hour, 2)
+	p.assertPrice(usd(700))
+	p.assertStatus(first, models.PriceScheduleEnded)
+	if active := p.assertStatus(second, models.PriceScheduleActive); active.BaseAmount == nil || *active.BaseAmount != 1000 {
+		t.Errorf("second BaseAmount = %v, want 1000", active.BaseAmount)
+	}
+
+	p.apply(2*time.Hour, 1)
+	p.assertPrice(usd(1000))
+}
+
+func TestOverlappingPriceScheduleRejected(t *testing.T) {
+	p := newPriceTest(t)
+	p.schedule(800, 0, time.Hour)
+
+	ends := p.at(2 * time.Hour)
+	overlapping := &models.PriceSchedule{ProductID: p.product.ID, Price: usd(700), StartsAt: p.at(30 * time.Minute), EndsAt: &ends}
+	if err := p.repo.CreatePriceSchedule(context.Background(), overlapping); !errors.Is(err, ErrPriceScheduleOverlap) {
+		t.Errorf("CreatePriceSchedule error = %v, want ErrPriceScheduleOverlap", err)
+	}
+}
+
+func TestCancelPriceSchedule(t *testing.T) {
+	p := newPriceTest(t)
+	ctx := context.Background()
+	pending := p.schedule(700, 2*time.Hour, 3*time.Hour)
+	active := p.schedule(800, 0, time.Hour)
+	p.apply(0, 1)
+
+	// Cancelling an active schedule restores the base price at once
+	if _, err := p.repo.CancelPriceSchedule(ctx, p.product.ID, active.ID, p.at(10*time.Minute)); err != nil {
+		t.Fatalf("CancelPriceSchedule(active): %v", err)
+	}
+	p.assertPrice(usd(1000))
+	p.assertStatus(active, models.PriceScheduleCancelled)
+
+	if _, err := p.repo.CancelPriceSchedule(ctx, p.product.ID, pending.ID, p.at(10*time.Minute)); err != nil {
+		t.Fatalf("CancelPriceSchedule(pending): %v", err)
+	}
+	p.assertStatus(pending, models.PriceScheduleCancelled)
+
+	// Cancelled schedules are neither applied nor cancelled again
+	p.apply(3*time.Hour, 0)
+	p.assertPrice(usd(1000))
+	if _, err := p.repo.CancelPriceSchedule(ctx, p.product.ID, active.ID, p.at(time.Hour)); !errors.Is(err, ErrPriceScheduleFinished) {
+		t.Errorf("cancelling twice: error = %v, want ErrPriceScheduleFinished", err)
+	}
+	if _, err := p.repo.CancelPriceSchedule(ctx, p.product.ID+1, pending.ID, p.at(time.Hour)); !errors.Is(err, ErrPriceScheduleNotFound) {
+		t.Errorf("cancelling through another product: error = %v, want ErrPriceScheduleNotFound", err)
+	}
+}
+
+func TestPriceScheduleCurrencyMismatch(t *testing.T) {
+	p := newPriceTest(t)
+	ctx := context.Background()
+
+	ends := p.at(time.Hour)
+	euros := &models.PriceSchedule{ProductID: p.product.ID, Price: money.Money{Amount: 800, Currency: "EUR"}, StartsAt: p.at(0), EndsAt: &ends}
+	if err := p.repo.CreatePriceSchedule(ctx, euros); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("CreatePriceSchedule in EUR: error = %v, want ErrCurrencyMismatch", err)
+	}
+
+	// A schedule whose currency the product dropped is cancelled when due
+	sale := p.schedule(800, 0, time.Hour)
+	p.product.Price = money.Money{Amount: 950, Currency: "EUR"}
+	if err := p.repo.Update(ctx, p.product, false); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	p.apply(0, 1)
+	p.assertPrice(money.Money{Amount: 950, Currency: "EUR"})
+	p.assertStatus(sale, models.PriceScheduleCancelled)
+}
+
+func TestMissedPriceScheduleEndsWithoutChange(t *testing.T) {
+	p := newPriceTest(t)
+	missed := p.schedule(800, 0, time.Hour)
+
+	// The whole window passed before the scheduler ran
+	p.apply(2*time.Hour, 1)
+	p.assertPrice(usd(1000))
+	p.assertStatus(missed, models.PriceScheduleEnded)
+}
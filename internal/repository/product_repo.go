@@ -3,11 +3,18 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"product-management-system/internal/models"
+	"slices"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrProductNotFound = errors.New("product not found")
+
 type ProductRepository struct {
 	db *gorm.DB
 }
@@ -16,8 +23,24 @@ func NewProductRepository(db *gorm.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
+// Create inserts the product, starts its price history and queues
+// processing of its images in the outbox
 func (r *ProductRepository) Create(ctx context.Context, product *models.Product) error {
-	return r.db.WithContext(ctx).Create(product).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		if err := recordPriceChange(tx, product.ID, product.Price, models.PriceSourceManual, nil, product.CreatedAt); err != nil {
+			return err
+		}
+		if len(product.ProductImages) == 0 {
+			return nil
+		}
+		return enqueueImageProcessing(tx, &models.ImageProcessingTask{
+			ProductID: product.ID,
+			ImageURLs: product.ProductImages,
+		}, product.CreatedAt)
+	})
 }
 
 func (r *ProductRepository) FindByID(ctx context.Context, id uint) (*models.Product, error) {
@@ -25,35 +48,298 @@ func (r *ProductRepository) FindByID(ctx context.Context, id uint) (*models.Prod
 	result := r.db.WithContext(ctx).First(&product, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
+			return nil, ErrProductNotFound
 		}
 		return nil, result.Error
 	}
 	return &product, nil
 }
 
-func (r *ProductRepository) FindByUserID(ctx context.Context, userID uint, filters map[string]interface{}) ([]models.Product, error) {
+// List returns one page of products matching the filter along with the
+// total number of matches. Pages are fetched with Offset or, for stable
+// deep pagination, with a keyset Cursor from a previous page.
+func (r *ProductRepository) List(ctx context.Context, filter ProductFilter) (*ProductPage, error) {
+	if filter.Limit <= 0 || filter.Limit > MaxListLimit {
+		filter.Limit = DefaultListLimit
+	}
+
+	query := r.applyFilter(r.db.WithContext(ctx).Model(&models.Product{}), filter)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if filter.Cursor != nil {
+		operator := ">"
+		if filter.Descending {
+			operator = "<"
+		}
+		if filter.Sort == SortPrice {
+			// The inner products shadows the outer one in the price expression
+			query = query.Where(fmt.Sprintf("(%[1]s, products.id) %[2]s ((SELECT %[1]s FROM products WHERE products.id = ?), ?)",
+				filter.sortColumn(), operator), filter.Cursor.ID, filter.Cursor.ID)
+		} else {
+			value, err := filter.Cursor.value()
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", filter.sortColumn(), operator), value, filter.Cursor.ID)
+		}
+	}
+
+	// Fetch one extra row to find out whether there is a next page
 	var products []models.Product
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	result := query.Order(filter.orderClause()).
+		Offset(filter.Offset).
+		Limit(filter.Limit + 1).
+		Find(&products)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	page := &ProductPage{Products: products, Total: total}
+	if len(products) > filter.Limit {
+		page.Products = products[:filter.Limit]
+		cursor, err := EncodeCursor(&page.Products[filter.Limit-1], filter.Sort, filter.Descending, filter.Currency)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor
+	}
+
+	return page, nil
+}
+
+func (r *ProductRepository) applyFilter(query *gorm.DB, filter ProductFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	if filter.MinPrice != nil {
+		query = query.Where(filter.Prices.expr()+" >= ?::numeric", decimalArg(*filter.MinPrice))
+	}
+
+	if filter.MaxPrice != nil {
+		query = query.Where(filter.Prices.expr()+" <= ?::numeric", decimalArg(*filter.MaxPrice))
+	}
 
-	if minPrice, ok := filters["min_price"].(float64); ok {
-		query = query.Where("product_price >= ?", minPrice)
+	if filter.Visibility != "" {
+		query = query.Where("visibility = ?", filter.Visibility)
 	}
 
-	if maxPrice, ok := filters["max_price"].(float64); ok {
-		query = query.Where("product_price <= ?", maxPrice)
+	if filter.ProductName != "" {
+		query = query.Where("product_name ILIKE ?", "%"+escapeLike(filter.ProductName)+"%")
 	}
 
-	if productName, ok := filters["product_name"].(string); ok {
-		query = query.Where("product_name ILIKE ?", "%"+productName+"%")
+	// Products in the category or any of its descendants
+	if filter.CategoryID != nil {
+		query = query.Where(`category_id IN (
+			SELECT id FROM categories
+			WHERE deleted_at IS NULL
+			AND path LIKE (SELECT path FROM categories WHERE id = ?) || '%')`, *filter.CategoryID)
 	}
 
-	result := query.Find(&products)
-	return products, result.Error
+	// One containment check per tag so the GIN index can be used
+	for _, tag := range filter.Tags {
+		query = query.Where("tags @> ARRAY[?]::text[]", tag)
+	}
+
+	for key, value := range filter.Attributes {
+		candidates := attributeCandidates(key, value)
+		conditions := make([]string, len(candidates))
+		args := make([]interface{}, len(candidates))
+		for i, candidate := range candidates {
+			conditions[i] = "attributes @> ?::jsonb"
+			args[i] = candidate
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	return query
 }
 
-func (r *ProductRepository) UpdateProductImages(ctx context.Context, productID uint, compressedImages []string) error {
+// MergeProcessedImages stores processing results for some of a product's
+// images under a row lock, so concurrent tasks for the same product don't
+// overwrite each other. Results for images the product no longer has are
+// dropped, and the product is marked done once every image has results.
+func (r *ProductRepository) MergeProcessedImages(ctx context.Context, productID uint, processedImages []models.ProcessedImage, processedAt time.Time) (*models.Product, error) {
+	var product models.Product
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		bySource := make(map[string]models.ProcessedImage)
+		for _, image := range product.ProcessedImages {
+			bySource[image.SourceURL] = image
+		}
+		for _, image := range processedImages {
+			bySource[image.SourceURL] = image
+		}
+
+		// Keep results in the order of the product's images
+		merged := make([]models.ProcessedImage, 0, len(product.ProductImages))
+		for _, url := range product.ProductImages {
+			if image, ok := bySource[url]; ok {
+				merged = append(merged, image)
+			}
+		}
+
+		product.ProcessedImages = merged
+		product.ProcessedAt = &processedAt
+		columns := []string{"processed_images", "processed_at"}
+		if len(merged) == len(product.ProductImages) {
+			product.ImageStatus = models.ImageStatusDone
+			product.ImageError = ""
+			columns = append(columns, "image_status", "image_error")
+		}
+
+		// Update from a struct so ProcessedImages goes through its JSON serializer
+		return tx.Model(&product).Select(columns).Updates(&product).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// AppendProductImage adds an image to a product, marks its images as
+// pending and queues processing of the new image in the outbox. It returns
+// false if the product already has the image.
+func (r *ProductRepository) AppendProductImage(ctx context.Context, productID uint, imageURL string) (bool, error) {
+	added := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Product{}).
+			Where("id = ? AND NOT (? = ANY(COALESCE(product_images, '{}')))", productID, imageURL).
+			Updates(map[string]interface{}{
+				"product_images": gorm.Expr("array_append(product_images, ?)", imageURL),
+				"image_status":   models.ImageStatusPending,
+				"image_error":    "",
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		added = true
+		return enqueueImageProcessing(tx, &models.ImageProcessingTask{
+			ProductID: productID,
+			ImageURLs: []string{imageURL},
+			Append:    true,
+		}, time.Now())
+	})
+	return added, err
+}
+
+// UpdateImageStatus records an intermediate or failed processing state
+func (r *ProductRepository) UpdateImageStatus(ctx context.Context, productID uint, status string, errorMessage string) error {
 	return r.db.WithContext(ctx).Model(&models.Product{}).
 		Where("id = ?", productID).
-		Update("compressed_product_images", compressedImages).Error
+		Updates(map[string]interface{}{
+			"image_status": status,
+			"image_error":  errorMessage,
+		}).Error
+}
+
+// productEditableColumns are the columns Update writes. Image results,
+// reservations and the price range belong to the image worker, the
+// inventory and the variants, which may change them while an edit is in
+// flight.
+var productEditableColumns = []string{
+	"product_name", "product_description", "price_amount", "price_currency",
+	"stock", "visibility", "category_id", "tags", "attributes",
+}
+
+// productImageColumns are written by Update only when the images are
+// replaced, and are reloaded afterwards otherwise
+var productImageColumns = []string{
+	"product_images", "processed_images", "image_status", "image_error", "processed_at",
+}
+
+// Update saves the product's editable fields, records a price change and
+// recomputes its price range, since variants without their own price
+// follow the product's price. The currency can only change while no
+// variant has its own price. With reprocessImages, the product's images
+// and their reset processing state are saved too, and processing of all
+// of them is queued in the outbox. Columns Update doesn't write are
+// reloaded into product.
+func (r *ProductRepository) Update(ctx context.Context, product *models.Product, reprocessImages bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "reserved", "price_amount", "price_currency").
+			First(&current, product.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProductNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if product.Stock < current.Reserved {
+			return ErrStockBelowReserved
+		}
+		// Variant prices are minor units of the product's currency
+		if product.Price.Currency != current.Price.Currency {
+			var priced int64
+			err := tx.Model(&models.ProductVariant{}).
+				Where("product_id = ? AND price_amount IS NOT NULL", product.ID).
+				Count(&priced).Error
+			if err != nil {
+				return err
+			}
+			if priced > 0 {
+				return ErrVariantPricesSet
+			}
+		}
+
+		columns := productEditableColumns
+		if reprocessImages {
+			columns = append(slices.Clone(columns), productImageColumns...)
+		}
+		// Update from a struct so the JSON columns go through their serializer
+		if err := tx.Model(product).Select(columns).Updates(product).Error; err != nil {
+			return err
+		}
+		if product.Price != current.Price {
+			err := recordPriceChange(tx, product.ID, product.Price, models.PriceSourceManual, nil, product.UpdatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		if err := refreshPriceRange(tx, product.ID); err != nil {
+			return err
+		}
+		if reprocessImages && len(product.ProductImages) > 0 {
+			err := enqueueImageProcessing(tx, &models.ImageProcessingTask{
+				ProductID: product.ID,
+				ImageURLs: product.ProductImages,
+			}, product.UpdatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		reload := []string{"reserved", "price_min_amount", "price_max_amount"}
+		if !reprocessImages {
+			reload = append(reload, productImageColumns...)
+		}
+		return tx.Select(reload).First(product, product.ID).Error
+	})
+}
+
+// Delete soft-deletes the product and its variants by setting
+// gorm.Model.DeletedAt
+func (r *ProductRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Product{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrProductNotFound
+		}
+		return tx.Where("product_id = ?", id).Delete(&models.ProductVariant{}).Error
+	})
 }
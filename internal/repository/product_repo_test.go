@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+)
+
+func TestUpdateKeepsConcurrentImageResults(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	const imageURL = "https://example.com/a.jpg"
+	result := models.ProcessedImage{
+		SourceURL: imageURL,
+		Variants:  []models.ImageVariant{{Name: "thumbnail", URL: "https://cdn.example.com/a.webp"}},
+	}
+
+	for i := 0; i < 20; i++ {
+		product := &models.Product{
+			UserID:        1,
+			ProductName:   "Lamp",
+			ProductImages: []string{imageURL},
+			Price:         money.Money{Amount: 1000, Currency: "USD"},
+			Stock:         5,
+			ImageStatus:   models.ImageStatusPending,
+		}
+		if err := repo.Create(ctx, product); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		// Edit a copy loaded before the worker's results arrive
+		edited, err := repo.FindByID(ctx, product.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		edited.ProductName = "Desk lamp"
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := repo.MergeProcessedImages(ctx, product.ID, []models.ProcessedImage{result}, time.Now())
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- repo.Update(ctx, edited, false)
+		}()
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("concurrent write: %v", err)
+			}
+		}
+
+		stored, err := repo.FindByID(ctx, product.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if stored.ProductName != "Desk lamp" {
+			t.Errorf("ProductName = %q, want %q", stored.ProductName, "Desk lamp")
+		}
+		if stored.ImageStatus != models.ImageStatusDone || len(stored.ProcessedImages) != 1 {
+			t.Errorf("image results lost: status %q, %d processed images", stored.ImageStatus, len(stored.ProcessedImages))
+		}
+	}
+}
+
+func TestUpdateKeepsCurrencyWhileVariantsArePriced(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{UserID: 1, ProductName: "Tee", Price: money.Money{Amount: 1000, Currency: "JPY"}, Stock: 5}
+	if err := repo.Create(ctx, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	override := int64(1200)
+	variant := &models.ProductVariant{ProductID: product.ID, SKU: "TEE-M", Options: map[string]string{"size": "M"}, PriceAmount: &override}
+	if err := repo.CreateVariant(ctx, variant); err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+
+	product.Price = money.Money{Amount: 10, Currency: "USD"}
+	if err := repo.Update(ctx, product, false); !errors.Is(err, ErrVariantPricesSet) {
+		t.Fatalf("Update to USD error = %v, want ErrVariantPricesSet", err)
+	}
+	stored, err := repo.FindByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.Price.Currency != "JPY" {
+		t.Errorf("currency = %s, want JPY", stored.Price.Currency)
+	}
+
+	// Changing the amount alone is fine
+	product.Price = money.Money{Amount: 1100, Currency: "JPY"}
+	if err := repo.Update(ctx, product, false); err != nil {
+		t.Fatalf("Update amount: %v", err)
+	}
+
+	// Once the variant follows the product's price, the currency can change
+	variant.PriceAmount = nil
+	if err := repo.UpdateVariant(ctx, variant); err != nil {
+		t.Fatalf("UpdateVariant: %v", err)
+	}
+	product.Price = money.Money{Amount: 10, Currency: "USD"}
+	if err := repo.Update(ctx, product, false); err != nil {
+		t.Fatalf("Update to USD: %v", err)
+	}
+	if product.PriceMinAmount == nil || *product.PriceMinAmount != 10 {
+		t.Errorf("PriceMinAmount = %v, want 10", product.PriceMinAmount)
+	}
+}
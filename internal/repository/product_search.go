@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"product-management-system/internal/models"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// searchConfig is the text search configuration used for both the index
+// and queries; they must match for the GIN index to be used
+const searchConfig = "english"
+
+// Highlight delimiters passed to ts_headline. Control characters can't be
+// confused with product text, and are turned into <mark> tags after the
+// text around them has been HTML-escaped.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+var ErrEmptySearchQuery = errors.New("search query must contain at least one word")
+
+// PriceBucketBounds are the upper bounds of the price facet buckets, in
+// major units of the search's currency. The last bucket is open-ended.
+var PriceBucketBounds = []float64{10, 25, 50, 100, 250, 500, 1000}
+
+// SearchFilter describes a full-text product search
+type SearchFilter struct {
+	Query string
+	// Prices converts prices for the price range and facets
+	Prices   PriceScale
+	MinPrice *float64
+	MaxPrice *float64
+
+	// Public products are always searched. ViewerID adds that user's own
+	// products of any visibility and IncludeHidden adds everyone's.
+	ViewerID      uint
+	IncludeHidden bool
+
+	Limit  int
+	Offset int
+}
+
+// SearchHit is a matching product with its rank and highlighted text
+type SearchHit struct {
+	Product            models.Product
+	Rank               float64
+	NameHighlight      string
+	DescriptionSnippet string
+}
+
+// PriceBucket counts matches with Min <= price < Max. Max is nil for the
+// last bucket.
+type PriceBucket struct {
+	Min   float64
+	Max   *float64
+	Count int64
+}
+
+// SearchResult is one page of search hits plus facets over all matches
+type SearchResult struct {
+	Hits        []SearchHit
+	Total       int64
+	PriceFacets []PriceBucket
+}
+
+// MigrateProductSearch adds the weighted search vector over product name
+// and description and its GIN index. AutoMigrate can't express generated
+// columns, so this runs after it.
+func MigrateProductSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('` + searchConfig + `', coalesce(product_name, '')), 'A') ||
+				setweight(to_tsvector('` + searchConfig + `', coalesce(product_description, '')), 'B')
+			) STORED`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`).Error
+	})
+}
+
+// Search finds products matching every word of the query, treating each
+// word as a prefix. Name matches rank above description matches.
+func (r *ProductRepository) Search(ctx context.Context, filter SearchFilter) (*SearchResult, error) {
+	tsQuery := prefixTSQuery(filter.Query)
+	if tsQuery == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxListLimit {
+		filter.Limit = DefaultListLimit
+	}
+
+	// Facets ignore the price range so clients can show how to widen it
+	matches := r.db.WithContext(ctx).Model(&models.Product{}).
+		Where("search_vector @@ to_tsquery('"+searchConfig+"', ?)", tsQuery)
+	matches = searchVisibility(matches, filter)
+
+	price := filter.Prices.expr()
+	facets, err := r.priceFacets(matches.Session(&gorm.Session{}), price)
+	if err != nil {
+		return nil, err
+	}
+
+	query := matches.Session(&gorm.Session{})
+	if filter.MinPrice != nil {
+		query = query.Where(price+" >= ?::numeric", decimalArg(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where(price+" <= ?::numeric", decimalArg(*filter.MaxPrice))
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID                 uint
+		Rank               float64
+		NameHighlight      string
+		DescriptionSnippet string
+	}
+	highlight := "StartSel=" + highlightStart + ", StopSel=" + highlightStop
+	err = query.
+		Select(fmt.Sprintf(`id,
+			ts_rank(search_vector, to_tsquery('%[1]s', @query)) AS rank,
+			ts_headline('%[1]s', product_name, to_tsquery('%[1]s', @query), @name_options) AS name_highlight,
+			ts_headline('%[1]s', coalesce(product_description, ''), to_tsquery('%[1]s', @query), @snippet_options) AS description_snippet`,
+			searchConfig),
+			sql.Named("query", tsQuery),
+			sql.Named("name_options", highlight+", HighlightAll=true"),
+			sql.Named("snippet_options", highlight+`, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=" … "`)).
+		Order("rank DESC, id DESC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Total: total, PriceFacets: facets}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	// Load the full products separately so their serialized fields are
+	// decoded the same way as everywhere else
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var products []models.Product
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, row := range rows {
+		product, ok := byID[row.ID]
+		if !ok {
+			continue // deleted between the two queries
+		}
+		result.Hits = append(result.Hits, SearchHit{
+			Product:            product,
+			Rank:               row.Rank,
+			NameHighlight:      markHighlights(row.NameHighlight),
+			DescriptionSnippet: markHighlights(row.DescriptionSnippet),
+		})
+	}
+	return result, nil
+}
+
+// priceFacets counts matches per PriceBucketBounds bucket of price, a SQL
+// price expression
+func (r *ProductRepository) priceFacets(matches *gorm.DB, price string) ([]PriceBucket, error) {
+	var counts []struct {
+		Bucket int
+		Count  int64
+	}
+	err := matches.
+		// Prices in currencies without a rate have no bucket
+		Select("coalesce(width_bucket("+price+", ?::numeric[]), -1) AS bucket, count(*) AS count", priceBoundsArray()).
+		Group("bucket").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]PriceBucket, len(PriceBucketBounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = PriceBucketBounds[i-1]
+		}
+		if i < len(PriceBucketBounds) {
+			buckets[i].Max = &PriceBucketBounds[i]
+		}
+	}
+	for _, c := range counts {
+		if c.Bucket >= 0 && c.Bucket < len(buckets) {
+			buckets[c.Bucket].Count += c.Count
+		}
+	}
+	return buckets, nil
+}
+
+func searchVisibility(query *gorm.DB, filter SearchFilter) *gorm.DB {
+	if filter.IncludeHidden {
+		return query
+	}
+	if filter.ViewerID != 0 {
+		return query.Where("(visibility = ? OR user_id = ?)", models.VisibilityPublic, filter.ViewerID)
+	}
+	return query.Where("visibility = ?", models.VisibilityPublic)
+}
+
+// prefixTSQuery turns free text into a tsquery requiring every word as a
+// prefix, e.g. "red shoe" becomes "red:* & shoe:*". Everything but letters
+// and digits is dropped so user input can't inject tsquery operators.
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// markHighlights HTML-escapes a ts_headline result and wraps the matched
+// terms in <mark> tags
+func markHighlights(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
+
+// priceBoundsArray formats PriceBucketBounds as a PostgreSQL array literal
+func priceBoundsArray() string {
+	bounds := make([]string, len(PriceBucketBounds))
+	for i, bound := range PriceBucketBounds {
+		bounds[i] = strconv.FormatFloat(bound, 'f', -1, 64)
+	}
+	return "{" + strings.Join(bounds, ",") + "}"
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+)
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"red shoe", "red:* & shoe:*"},
+		{"  Red   SHOE  ", "Red:* & SHOE:*"},
+		{"café crème", "café:* & crème:*"},
+		{"size 42", "size:* & 42:*"},
+		{"t-shirt", "t:* & shirt:*"},
+		// tsquery operators and syntax are dropped
+		{"red | shoe", "red:* & shoe:*"},
+		{"!red & (shoe <-> boot)", "red:* & shoe:* & boot:*"},
+		{"red:* shoe:A", "red:* & shoe:* & A:*"},
+		{"'red' \\shoe", "red:* & shoe:*"},
+		{"", ""},
+		{"!&|()<->:*'", ""},
+	}
+	for _, tt := range tests {
+		if got := prefixTSQuery(tt.text); got != tt.want {
+			t.Errorf("prefixTSQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMarkHighlights(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{"plain text", "plain text"},
+		{"a \x02red\x03 shoe", "a <mark>red</mark> shoe"},
+		{"\x02red\x03 and \x02blue\x03", "<mark>red</mark> and <mark>blue</mark>"},
+		{"<script>\x02alert\x03</script>", "&lt;script&gt;<mark>alert</mark>&lt;/script&gt;"},
+		{"Tom & \"Jerry's\"", "Tom &amp; &#34;Jerry&#39;s&#34;"},
+		{"<mark>fake</mark>", "&lt;mark&gt;fake&lt;/mark&gt;"},
+	}
+	for _, tt := range tests {
+		if got := markHighlights(tt.headline); got != tt.want {
+			t.Errorf("markHighlights(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
+
+func TestSearchIgnoresOperatorsAndEscapesHighlights(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{
+		UserID:             1,
+		ProductName:        "Red <b>shoe</b>",
+		ProductDescription: "A comfortable red shoe & more",
+		Price:              money.Money{Amount: 1000, Currency: "USD"},
+		Visibility:         models.VisibilityPublic,
+	}
+	if err := repo.Create(ctx, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		found int
+	}{
+		{"red", 1},
+		{"re sh", 1},
+		{"RED:*", 1},
+		// Every word is required, so | doesn't widen the search
+		{"red | nothing", 0},
+		{"!red & (shoe <-> x", 0},
+		{"(shoe)", 1},
+	}
+	for _, tt := range tests {
+		result, err := repo.Search(ctx, SearchFilter{Query: tt.query})
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if len(result.Hits) != tt.found {
+			t.Fatalf("Search(%q) found %d products, want %d", tt.query, len(result.Hits), tt.found)
+		}
+		if tt.found == 0 {
+			continue
+		}
+		highlight := result.Hits[0].NameHighlight
+		if strings.Contains(highlight, "<b>") || !strings.Contains(highlight, "<mark>") {
+			t.Errorf("Search(%q) name highlight = %q, want escaped markup and a <mark>", tt.query, highlight)
+		}
+	}
+
+	if _, err := repo.Search(ctx, SearchFilter{Query: "&|!"}); !errors.Is(err, ErrEmptySearchQuery) {
+		t.Errorf("Search of operators only: error = %v, want ErrEmptySearchQuery", err)
+	}
+}
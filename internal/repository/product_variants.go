@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"product-management-system/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pgUniqueViolation is the PostgreSQL error code for unique_violation
+const pgUniqueViolation = "23505"
+
+var (
+	ErrVariantNotFound     = errors.New("variant not found")
+	ErrSKUExists           = errors.New("a variant with this SKU already exists")
+	ErrVariantOptionsExist = errors.New("the product already has a variant with these options")
+	ErrVariantPricesSet    = errors.New("the product's currency can't change while variants have their own price")
+)
+
+// ListVariants returns a product's variants in creation order
+func (r *ProductRepository) ListVariants(ctx context.Context, productID uint) ([]models.ProductVariant, error) {
+	var variants []models.ProductVariant
+	err := r.db.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("id").
+		Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (r *ProductRepository) FindVariant(ctx context.Context, productID, variantID uint) (*models.ProductVariant, error) {
+	return findVariant(r.db.WithContext(ctx), productID, variantID)
+}
+
+// CreateVariant adds a variant and updates the product's price range
+func (r *ProductRepository) CreateVariant(ctx context.Context, variant *models.ProductVariant) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, variant.ProductID); err != nil {
+			return err
+		}
+		if err := checkVariantUnique(tx, variant); err != nil {
+			return err
+		}
+		if err := tx.Create(variant).Error; err != nil {
+			return skuConflict(err)
+		}
+		return refreshPriceRange(tx, variant.ProductID)
+	})
+}
+
+// UpdateVariant saves a variant's editable fields and updates the
+// product's price range
+func (r *ProductRepository) UpdateVariant(ctx context.Context, variant *models.ProductVariant) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, variant.ProductID); err != nil {
+			return err
+		}
+		if _, err := findVariant(tx, variant.ProductID, variant.ID); err != nil {
+			return err
+		}
+		if err := checkVariantUnique(tx, variant); err != nil {
+			return err
+		}
+		// Reservations update the variant without locking the product, so
+		// the stock check has to be part of the update
+		result := tx.Model(variant).
+			Where("reserved <= ?", variant.Stock).
+			Select("sku", "options", "price_amount", "images", "stock").
+			Updates(variant)
+		if result.Error != nil {
+			return skuConflict(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrStockBelowReserved
+		}
+		return refreshPriceRange(tx, variant.ProductID)
+	})
+}
+
+// DeleteVariant soft-deletes a variant and updates the product's price range
+func (r *ProductRepository) DeleteVariant(ctx context.Context, productID, variantID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+		result := tx.Where("product_id = ?", productID).Delete(&models.ProductVariant{}, variantID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVariantNotFound
+		}
+		return refreshPriceRange(tx, productID)
+	})
+}
+
+func findVariant(db *gorm.DB, productID, variantID uint) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := db.Where("product_id = ?", productID).First(&variant, variantID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// lockProduct serializes variant changes of one product so uniqueness
+// checks and price range updates don't race
+func lockProduct(tx *gorm.DB, productID uint) error {
+	var product models.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&product, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// skuVariantIndex is the unique index gorm creates for ProductVariant.SKU
+const skuVariantIndex = "idx_product_variants_sku"
+
+// skuConflict turns a unique violation on the SKU index into ErrSKUExists.
+// checkVariantUnique only serializes variants of one product, so two
+// products can still race to insert the same SKU.
+func skuConflict(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == skuVariantIndex {
+		return ErrSKUExists
+	}
+	return err
+}
+
+// checkVariantUnique fails if another variant uses the same SKU, including
+// deleted ones, or the same product has a variant with the same options
+func checkVariantUnique(tx *gorm.DB, variant *models.ProductVariant) error {
+	var count int64
+	err := tx.Unscoped().Model(&models.ProductVariant{}).
+		Where("sku = ? AND id <> ?", variant.SKU, variant.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSKUExists
+	}
+
+	options := variant.Options
+	if options == nil {
+		options = models.VariantOptions{}
+	}
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&models.ProductVariant{}).
+		Where("product_id = ? AND id <> ?", variant.ProductID, variant.ID).
+		Where("coalesce(options, '{}'::jsonb) = ?::jsonb", string(encoded)).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVariantOptionsExist
+	}
+	return nil
+}
+
+// refreshPriceRange recomputes a product's PriceMinAmount and
+// PriceMaxAmount from its variants. Variants without a price of their own
+// count at the product's price. Both are NULL when the product has no
+// variants.
+func refreshPriceRange(tx *gorm.DB, productID uint) error {
+	return updatePriceRanges(tx, "WHERE p.id = @id", sql.Named("id", productID))
+}
+
+// updatePriceRanges recomputes the price range of the products matched by
+// where, a condition on products aliased as p
+func updatePriceRanges(tx *gorm.DB, where string, args ...interface{}) error {
+	return tx.Exec(`UPDATE products SET
+			price_min_amount = ranges.min_amount,
+			price_max_amount = ranges.max_amount
+		FROM (
+			SELECT p.id,
+				min(coalesce(v.price_amount, p.price_amount)) FILTER (WHERE v.id IS NOT NULL) AS min_amount,
+				max(coalesce(v.price_amount, p.price_amount)) FILTER (WHERE v.id IS NOT NULL) AS max_amount
+			FROM products p
+			LEFT JOIN product_variants v ON v.product_id = p.id AND v.deleted_at IS NULL
+			`+where+`
+			GROUP BY p.id
+		) AS ranges
+		WHERE products.id = ranges.id`, args...).Error
+}
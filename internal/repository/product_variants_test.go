@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/money"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestSKUConflict(t *testing.T) {
+	other := errors.New("connection reset")
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"sku violation", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: skuVariantIndex}, ErrSKUExists},
+		{"wrapped sku violation", fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: skuVariantIndex}), ErrSKUExists},
+		{"other index", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_other"}, nil},
+		{"other code", &pgconn.PgError{Code: "23503", ConstraintName: skuVariantIndex}, nil},
+		{"other error", other, other},
+	}
+	for _, tt := range tests {
+		got := skuConflict(tt.err)
+		if tt.want == nil {
+			// Unrelated errors pass through unchanged
+			if got != tt.err {
+				t.Errorf("%s: skuConflict = %v, want the error unchanged", tt.name, got)
+			}
+			continue
+		}
+		if !errors.Is(got, tt.want) {
+			t.Errorf("%s: skuConflict = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func createVariantProduct(t *testing.T, repo *ProductRepository) *models.Product {
+	t.Helper()
+	product := &models.Product{UserID: 1, ProductName: "Tee", Price: money.Money{Amount: 1000, Currency: "USD"}}
+	if err := repo.Create(context.Background(), product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return product
+}
+
+func TestVariantUniqueness(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+	tee := createVariantProduct(t, repo)
+	mug := createVariantProduct(t, repo)
+
+	create := func(productID uint, sku string, options models.VariantOptions) (*models.ProductVariant, error) {
+		variant := &models.ProductVariant{ProductID: productID, SKU: sku, Options: options}
+		return variant, repo.CreateVariant(ctx, variant)
+	}
+
+	medium, err := create(tee.ID, "TEE-M", models.VariantOptions{"size": "M"})
+	if err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+	deleted, err := create(tee.ID, "TEE-OLD", models.VariantOptions{"size": "XS"})
+	if err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+	if err := repo.DeleteVariant(ctx, tee.ID, deleted.ID); err != nil {
+		t.Fatalf("DeleteVariant: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		productID uint
+		sku       string
+		options   models.VariantOptions
+		err       error
+	}{
+		{"SKU of another product's variant", mug.ID, "TEE-M", models.VariantOptions{"size": "M"}, ErrSKUExists},
+		{"SKU of a deleted variant", tee.ID, "TEE-OLD", models.VariantOptions{"size": "L"}, ErrSKUExists},
+		{"same options", tee.ID, "TEE-M2", models.VariantOptions{"size": "M"}, ErrVariantOptionsExist},
+		{"options of a deleted variant", tee.ID, "TEE-XS", models.VariantOptions{"size": "XS"}, nil},
+		{"same options on another product", mug.ID, "MUG-M", models.VariantOptions{"size": "M"}, nil},
+		{"no options", tee.ID, "TEE-PLAIN", nil, nil},
+		{"empty options like no options", tee.ID, "TEE-PLAIN2", models.VariantOptions{}, ErrVariantOptionsExist},
+	}
+	for _, tt := range tests {
+		if _, err := create(tt.productID, tt.sku, tt.options); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	// A variant can keep its own SKU and options when updated
+	medium.Stock = 3
+	if err := repo.UpdateVariant(ctx, medium); err != nil {
+		t.Errorf("UpdateVariant: %v", err)
+	}
+}
+
+func TestConcurrentSKUAcrossProducts(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		products := []*models.Product{createVariantProduct(t, repo), createVariantProduct(t, repo)}
+		sku := fmt.Sprintf("RACE-%d", i)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, len(products))
+		for _, product := range products {
+			wg.Add(1)
+			go func(productID uint) {
+				defer wg.Done()
+				errs <- repo.CreateVariant(ctx, &models.ProductVariant{ProductID: productID, SKU: sku})
+			}(product.ID)
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, ErrSKUExists):
+				t.Fatalf("CreateVariant: %v", err)
+			}
+		}
+		if created != 1 {
+			t.Fatalf("%d variants created with SKU %s, want 1", created, sku)
+		}
+	}
+}
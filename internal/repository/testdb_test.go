@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"product-management-system/internal/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the PostgreSQL database in TEST_DATABASE_DSN,
+// migrates it and empties its tables. Tests that need a database are
+// skipped when the variable isn't set.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Category{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.StockReservation{},
+		&models.PriceChange{},
+		&models.PriceSchedule{},
+		&models.PriceListEntry{},
+		&models.OutboxMessage{},
+	)
+	if err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	if err := MigrateMoney(db, "USD"); err != nil {
+		t.Fatalf("failed to migrate prices: %v", err)
+	}
+	if err := MigrateProductSearch(db); err != nil {
+		t.Fatalf("failed to migrate product search: %v", err)
+	}
+
+	err = db.Exec(`TRUNCATE users, categories, products, product_variants, stock_reservations,
+		price_changes, price_schedules, price_list_entries, outbox_messages RESTART IDENTITY CASCADE`).Error
+	if err != nil {
+		t.Fatalf("failed to empty test database: %v", err)
+	}
+	return db
+}
@@ -9,6 +9,12 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
 type UserRepository struct {
 	db *gorm.DB
 }
@@ -22,7 +28,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	var existingUser models.User
 	result := r.db.WithContext(ctx).Where("username = ? OR email = ?", user.Username, user.Email).First(&existingUser)
 	if result.Error == nil {
-		return errors.New("user already exists")
+		return ErrUserExists
 	}
 
 	// Only return error if it's not a "not found" error
@@ -45,7 +51,7 @@ func (r *UserRepository) FindByID(ctx context.Context, id uint) (*models.User, e
 	result := r.db.WithContext(ctx).Preload("Products").First(&user, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, result.Error
 	}
@@ -57,7 +63,7 @@ func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*
 	result := r.db.WithContext(ctx).Where("username = ?", username).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, result.Error
 	}
@@ -89,7 +95,7 @@ func (r *UserRepository) Authenticate(ctx context.Context, username, password st
 
 	// Compare passwords
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	return user, nil
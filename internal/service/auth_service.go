@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"product-management-system/internal/cache"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"product-management-system/pkg/logger"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrRegistrationFields = errors.New("username, email and password are required")
+)
+
+// TokenClaims are the JWT claims carried by access and refresh tokens
+type TokenClaims struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the authenticated user's ID from the subject claim
+func (c *TokenClaims) UserID() uint {
+	id, _ := strconv.ParseUint(c.Subject, 10, 64)
+	return uint(id)
+}
+
+type claimsContextKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the authenticated user's claims
+func ContextWithClaims(ctx context.Context, claims *TokenClaims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the authenticated user's claims, if any
+func ClaimsFromContext(ctx context.Context) (*TokenClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*TokenClaims)
+	return claims, ok
+}
+
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type AuthService struct {
+	userRepo        *repository.UserRepository
+	redisCache      *cache.RedisCache
+	secret          []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	logger          *logger.Logger
+}
+
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	redisCache *cache.RedisCache,
+	secret string,
+	accessTokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	logger *logger.Logger,
+) *AuthService {
+	return &AuthService{
+		userRepo:        userRepo,
+		redisCache:      redisCache,
+		secret:          []byte(secret),
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+		logger:          logger,
+	}
+}
+
+func (s *AuthService) Register(ctx context.Context, user *models.User) error {
+	if user.Username == "" || user.Email == "" || user.Password == "" {
+		return ErrRegistrationFields
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if !errors.Is(err, repository.ErrUserExists) {
+			s.logger.Error("Failed to register user", "error", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *AuthService) Login(ctx context.Context, username, password string) (*TokenPair, error) {
+	user, err := s.userRepo.Authenticate(ctx, username, password)
+	if err != nil {
+		// Don't reveal whether the username exists
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, repository.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair. Refresh
+// tokens are single use: the presented token is revoked on success, and
+// of concurrent refreshes with the same token only the one that deletes
+// it succeeds.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := s.parseToken(refreshToken, refreshTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := s.redisCache.Delete(ctx, refreshTokenKey(claims.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if !active {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, claims.UserID())
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// Logout revokes the access token for the rest of its lifetime and, if
+// given, the refresh token it was issued with
+func (s *AuthService) Logout(ctx context.Context, accessClaims *TokenClaims, refreshToken string) error {
+	if ttl := time.Until(accessClaims.ExpiresAt.Time); ttl > 0 {
+		if err := s.redisCache.Set(ctx, revokedTokenKey(accessClaims.ID), true, ttl); err != nil {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	claims, err := s.parseToken(refreshToken, refreshTokenType)
+	if err != nil {
+		return err
+	}
+	if claims.Subject != accessClaims.Subject {
+		return ErrInvalidToken
+	}
+
+	if _, err := s.redisCache.Delete(ctx, refreshTokenKey(claims.ID)); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// Authenticate validates an access token and returns its claims
+func (s *AuthService) Authenticate(ctx context.Context, accessToken string) (*TokenClaims, error) {
+	claims, err := s.parseToken(accessToken, accessTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.redisCache.Exists(ctx, revokedTokenKey(claims.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User) (*TokenPair, error) {
+	now := time.Now()
+
+	accessToken, accessClaims, err := s.signToken(user, accessTokenType, now, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshClaims, err := s.signToken(user, refreshTokenType, now, s.refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Track the refresh token so it can be rotated and revoked
+	if err := s.redisCache.Set(ctx, refreshTokenKey(refreshClaims.ID), user.ID, s.refreshTokenTTL); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresAt:    accessClaims.ExpiresAt.Time,
+	}, nil
+}
+
+func (s *AuthService) signToken(user *models.User, tokenType string, now time.Time, ttl time.Duration) (string, *TokenClaims, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := &TokenClaims{
+		Username:  user.Username,
+		Role:      user.Role,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signed, claims, nil
+}
+
+func (s *AuthService) parseToken(tokenString, tokenType string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.TokenType != tokenType || claims.UserID() == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func refreshTokenKey(jti string) string {
+	return fmt.Sprintf("refresh_token:%s", jti)
+}
+
+func revokedTokenKey(jti string) string {
+	return fmt.Sprintf("revoked_token:%s", jti)
+}
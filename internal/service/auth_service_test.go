@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func newTestAuthService(t *testing.T) *AuthService {
+	return NewAuthService(nil, nil, testSecret, 15*time.Minute, time.Hour, testLogger())
+}
+
+// testUser is a user that only exists in tokens
+func testUser(id uint) *models.User {
+	user := &models.User{Username: "user" + strconv.Itoa(int(id)), Role: models.RoleUser}
+	user.ID = id
+	return user
+}
+
+// signTestToken signs claims with method and key, bypassing signToken
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims *TokenClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestTokenTypesAreNotInterchangeable(t *testing.T) {
+	s := newTestAuthService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	access, _, err := s.signToken(testUser(1), accessTokenType, now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, _, err := s.signToken(testUser(1), refreshTokenType, now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both are rejected before the cache is consulted
+	if _, err := s.Authenticate(ctx, refresh); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate(refresh token) error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.Refresh(ctx, access); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh(access token) error = %v, want ErrInvalidToken", err)
+	}
+
+	if _, err := s.parseToken(access, accessTokenType); err != nil {
+		t.Errorf("parseToken(access token) error = %v", err)
+	}
+	if _, err := s.parseToken(refresh, refreshTokenType); err != nil {
+		t.Errorf("parseToken(refresh token) error = %v", err)
+	}
+}
+
+func TestParseTokenRejectsInvalid(t *testing.T) {
+	s := newTestAuthService(t)
+	now := time.Now()
+	claims := func(mutate func(*TokenClaims)) *TokenClaims {
+		c := &TokenClaims{
+			Username:  "user1",
+			Role:      models.RoleUser,
+			TokenType: accessTokenType,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        "jti",
+				Subject:   "1",
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+		}
+		mutate(c)
+		return c
+	}
+	valid := func(*TokenClaims) {}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS384", signTestToken(t, jwt.SigningMethodHS384, []byte(testSecret), claims(valid))},
+		{"HS512", signTestToken(t, jwt.SigningMethodHS512, []byte(testSecret), claims(valid))},
+		{"none", signTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(valid))},
+		{"wrong secret", signTestToken(t, jwt.SigningMethodHS256, []byte("other"), claims(valid))},
+		{"missing exp", signTestToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims(func(c *TokenClaims) { c.ExpiresAt = nil }))},
+		{"expired", signTestToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims(func(c *TokenClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+		}))},
+		{"missing type", signTestToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims(func(c *TokenClaims) { c.TokenType = "" }))},
+		{"missing subject", signTestToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims(func(c *TokenClaims) { c.Subject = "" }))},
+		{"malformed", "not.a.token"},
+	}
+	for _, tt := range tests {
+		if _, err := s.parseToken(tt.token, accessTokenType); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: error = %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+
+	// The same claims signed with HS256 are accepted
+	token := signTestToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims(valid))
+	if _, err := s.parseToken(token, accessTokenType); err != nil {
+		t.Errorf("HS256: error = %v", err)
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	db := openTestDB(t, &models.User{}, &models.Product{})
+	redisCache := openTestRedis(t)
+	userRepo := repository.NewUserRepository(db)
+	s := NewAuthService(userRepo, redisCache, testSecret, 15*time.Minute, time.Hour, testLogger())
+	ctx := context.Background()
+
+	if err := s.Register(ctx, &models.User{Username: "alice", Email: "alice@example.com", Password: "secret"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	pair, err := s.Login(ctx, "alice", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	rotated, err := s.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("reusing a rotated refresh token: error = %v, want ErrInvalidToken", err)
+	}
+
+	// Of concurrent refreshes with the same token, exactly one succeeds
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Refresh(ctx, rotated.RefreshToken)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+	succeeded := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrInvalidToken):
+			t.Errorf("concurrent Refresh: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent refreshes succeeded, want 1", succeeded)
+	}
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	redisCache := openTestRedis(t)
+	s := NewAuthService(nil, redisCache, testSecret, 15*time.Minute, time.Hour, testLogger())
+	ctx := context.Background()
+
+	pair, err := s.issueTokens(ctx, testUser(1))
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+	claims, err := s.Authenticate(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if err := s.Logout(ctx, claims, pair.RefreshToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := s.Authenticate(ctx, pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate after Logout: error = %v, want ErrInvalidToken", err)
+	}
+	refreshClaims, err := s.parseToken(pair.RefreshToken, refreshTokenType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active, err := redisCache.Exists(ctx, refreshTokenKey(refreshClaims.ID)); err != nil || active {
+		t.Errorf("refresh token active after Logout = %v, %v", active, err)
+	}
+}
+
+func TestLogoutRejectsOtherUsersRefreshToken(t *testing.T) {
+	redisCache := openTestRedis(t)
+	s := NewAuthService(nil, redisCache, testSecret, 15*time.Minute, time.Hour, testLogger())
+	ctx := context.Background()
+
+	mine, err := s.issueTokens(ctx, testUser(1))
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+	theirs, err := s.issueTokens(ctx, testUser(2))
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+	claims, err := s.Authenticate(ctx, mine.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if err := s.Logout(ctx, claims, theirs.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Logout with another user's refresh token: error = %v, want ErrInvalidToken", err)
+	}
+	theirClaims, err := s.parseToken(theirs.RefreshToken, refreshTokenType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active, err := redisCache.Exists(ctx, refreshTokenKey(theirClaims.ID)); err != nil || !active {
+		t.Errorf("other user's refresh token active = %v, %v; want it kept", active, err)
+	}
+}
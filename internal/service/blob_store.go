@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Storage backends selectable with storage.backend
+const (
+	BlobBackendS3     = "s3"
+	BlobBackendLocal  = "local"
+	BlobBackendMemory = "memory"
+)
+
+var (
+	ErrBlobNotFound        = errors.New("blob not found")
+	ErrPresignNotSupported = errors.New("storage backend does not support presigned uploads")
+)
+
+// BlobInfo describes a stored object
+type BlobInfo struct {
+	Key          string
+	LastModified time.Time
+}
+
+// BlobStore stores image objects under slash-separated keys
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+	// URL returns the public URL of the object
+	URL(key string) string
+	// PresignGet returns a time-limited URL for reading a private object
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	// PresignPut returns a time-limited URL a client can PUT an object of
+	// the given content type to, or ErrPresignNotSupported
+	PresignPut(ctx context.Context, key string, contentType string, expires time.Duration) (string, error)
+}
+
+// BlobStoreConfig selects and configures a BlobStore backend
+type BlobStoreConfig struct {
+	Backend string
+
+	// S3 and S3-compatible stores
+	Bucket         string
+	Region         string
+	Endpoint       string
+	ForcePathStyle bool
+
+	// Local filesystem
+	LocalDir string
+
+	// Overrides the URL prefix returned by URL, e.g. for a CDN
+	PublicBaseURL string
+}
+
+// NewBlobStore builds the backend named by cfg.Backend, defaulting to S3
+func NewBlobStore(cfg BlobStoreConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", BlobBackendS3:
+		return NewS3BlobStore(cfg)
+	case BlobBackendLocal:
+		return NewLocalBlobStore(cfg.LocalDir, cfg.PublicBaseURL)
+	case BlobBackendMemory:
+		return NewMemoryBlobStore(cfg.PublicBaseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultLocalBlobURLPrefix is where the API serves a LocalBlobStore
+const DefaultLocalBlobURLPrefix = "/media"
+
+var ErrInvalidBlobKey = errors.New("invalid blob key")
+
+// LocalBlobStore keeps objects as files under a root directory. The API
+// serves the directory itself, so the worker and the API must share it.
+type LocalBlobStore struct {
+	root          string
+	publicBaseURL string
+}
+
+func NewLocalBlobStore(root string, publicBaseURL string) (*LocalBlobStore, error) {
+	if root == "" {
+		return nil, errors.New("storage.local.dir is required for the local backend")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	publicBaseURL = strings.TrimSuffix(publicBaseURL, "/")
+	if publicBaseURL == "" {
+		publicBaseURL = DefaultLocalBlobURLPrefix
+	}
+
+	return &LocalBlobStore{root: root, publicBaseURL: publicBaseURL}, nil
+}
+
+// Root returns the directory the store writes to
+func (s *LocalBlobStore) Root() string {
+	return s.root
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	filename, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	filename, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	filename, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// List walks only the directory holding prefix. Files deleted while it
+// walks are skipped.
+func (s *LocalBlobStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	// Cleaning under "/" keeps the walk inside root
+	dir := prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	start := filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+dir)))
+
+	var blobs []BlobInfo
+	err := filepath.WalkDir(start, func(filename string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, filename)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, BlobInfo{Key: key, LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+	return blobs, nil
+}
+
+func (s *LocalBlobStore) URL(key string) string {
+	return fmt.Sprintf("%s/%s", s.publicBaseURL, key)
+}
+
+// PresignGet returns the public URL since local objects are served as-is
+func (s *LocalBlobStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.URL(key), nil
+}
+
+// PresignPut is unsupported since the API only serves local objects for reading
+func (s *LocalBlobStore) PresignPut(ctx context.Context, key string, contentType string, expires time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// path maps a key to a file under root, rejecting keys that escape it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", ErrInvalidBlobKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStoreList(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := NewLocalBlobStore(filepath.Join(root, "blobs"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"products/1/a.webp", "products/1/thumb/b.webp", "products/10/c.webp", "other/d.webp"} {
+		if err := store.Put(ctx, key, strings.NewReader(key), "image/webp"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Files outside the store are never listed
+	if err := os.WriteFile(filepath.Join(root, "outside.webp"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"products/1/", []string{"products/1/a.webp", "products/1/thumb/b.webp"}},
+		{"products/1", []string{"products/1/a.webp", "products/1/thumb/b.webp", "products/10/c.webp"}},
+		{"products/1/thumb/", []string{"products/1/thumb/b.webp"}},
+		{"products/1/a", []string{"products/1/a.webp"}},
+		{"products/2/", nil},
+		{"missing/dir/", nil},
+		{"../", nil},
+		{"", []string{"other/d.webp", "products/1/a.webp", "products/1/thumb/b.webp", "products/10/c.webp"}},
+	}
+	for _, tt := range tests {
+		blobs, err := store.List(ctx, tt.prefix)
+		if err != nil {
+			t.Fatalf("List(%q): %v", tt.prefix, err)
+		}
+		var keys []string
+		for _, blob := range blobs {
+			keys = append(keys, blob.Key)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, tt.want) {
+			t.Errorf("List(%q) = %v, want %v", tt.prefix, keys, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryBlobStore keeps objects in process memory. It is meant for tests
+// and local experiments; nothing survives a restart.
+type MemoryBlobStore struct {
+	mu            sync.RWMutex
+	objects       map[string]memoryBlob
+	publicBaseURL string
+}
+
+type memoryBlob struct {
+	data         []byte
+	lastModified time.Time
+}
+
+func NewMemoryBlobStore(publicBaseURL string) *MemoryBlobStore {
+	publicBaseURL = strings.TrimSuffix(publicBaseURL, "/")
+	if publicBaseURL == "" {
+		publicBaseURL = "memory://"
+	}
+
+	return &MemoryBlobStore{
+		objects:       make(map[string]memoryBlob),
+		publicBaseURL: publicBaseURL,
+	}
+}
+
+func (s *MemoryBlobStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = memoryBlob{data: data, lastModified: time.Now()}
+	return nil
+}
+
+func (s *MemoryBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blob, ok := s.objects[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(blob.data)), nil
+}
+
+func (s *MemoryBlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *MemoryBlobStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var blobs []BlobInfo
+	for key, blob := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			blobs = append(blobs, BlobInfo{Key: key, LastModified: blob.lastModified})
+		}
+	}
+	return blobs, nil
+}
+
+func (s *MemoryBlobStore) URL(key string) string {
+	if strings.HasSuffix(s.publicBaseURL, "//") {
+		return s.publicBaseURL + key
+	}
+	return fmt.Sprintf("%s/%s", s.publicBaseURL, key)
+}
+
+func (s *MemoryBlobStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.URL(key), nil
+}
+
+func (s *MemoryBlobStore) PresignPut(ctx context.Context, key string, contentType string, expires time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3BlobStore stores objects in an S3 bucket or an S3-compatible store
+// such as MinIO when an endpoint is configured
+type S3BlobStore struct {
+	client        *s3.S3
+	uploader      *s3manager.Uploader
+	bucket        string
+	publicBaseURL string
+}
+
+func NewS3BlobStore(cfg BlobStoreConfig) (*S3BlobStore, error) {
+	awsConfig := &aws.Config{
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.ForcePathStyle),
+	}
+	if cfg.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(cfg.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS session: %w", err)
+	}
+
+	client := s3.New(sess)
+
+	publicBaseURL := strings.TrimSuffix(cfg.PublicBaseURL, "/")
+	if publicBaseURL == "" {
+		switch {
+		case cfg.Endpoint != "" && cfg.ForcePathStyle:
+			publicBaseURL = fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.Endpoint, "/"), cfg.Bucket)
+		case cfg.Endpoint != "":
+			scheme, host, _ := strings.Cut(strings.TrimSuffix(cfg.Endpoint, "/"), "://")
+			publicBaseURL = fmt.Sprintf("%s://%s.%s", scheme, cfg.Bucket, host)
+		default:
+			publicBaseURL = fmt.Sprintf("https://%s.s3.amazonaws.com", cfg.Bucket)
+		}
+	}
+
+	return &S3BlobStore{
+		client:        client,
+		uploader:      s3manager.NewUploaderWithClient(client),
+		bucket:        cfg.Bucket,
+		publicBaseURL: publicBaseURL,
+	}, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to S3: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrBlobNotFound
+		}
+		return nil, fmt.Errorf("failed to get %s from S3: %w", key, err)
+	}
+	return out.Body, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s from S3: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	var blobs []BlobInfo
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			blobs = append(blobs, BlobInfo{
+				Key:          aws.StringValue(obj.Key),
+				LastModified: aws.TimeValue(obj.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s in S3: %w", prefix, err)
+	}
+	return blobs, nil
+}
+
+func (s *S3BlobStore) URL(key string) string {
+	return fmt.Sprintf("%s/%s", s.publicBaseURL, key)
+}
+
+func (s *S3BlobStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	req.SetContext(ctx)
+	return req.Presign(expires)
+}
+
+func (s *S3BlobStore) PresignPut(ctx context.Context, key string, contentType string, expires time.Duration) (string, error) {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	req.SetContext(ctx)
+	return req.Presign(expires)
+}
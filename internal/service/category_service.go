@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"product-management-system/pkg/logger"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrCategoryNameRequired = errors.New("category name is required")
+	ErrInvalidSlug          = errors.New("slug may only contain lowercase letters, digits and hyphens")
+)
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type CategoryService struct {
+	categoryRepo *repository.CategoryRepository
+	logger       *logger.Logger
+}
+
+func NewCategoryService(categoryRepo *repository.CategoryRepository, logger *logger.Logger) *CategoryService {
+	return &CategoryService{
+		categoryRepo: categoryRepo,
+		logger:       logger,
+	}
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, category *models.Category) error {
+	if err := normalizeCategory(category); err != nil {
+		return err
+	}
+
+	if err := s.categoryRepo.Create(ctx, category); err != nil {
+		if !isCategoryClientError(err) {
+			s.logger.Error("Failed to create category", "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *CategoryService) FindCategoryByID(ctx context.Context, id uint) (*models.Category, error) {
+	return s.categoryRepo.FindByID(ctx, id)
+}
+
+// ListCategories returns all categories, or the subtree under rootID, in
+// tree order
+func (s *CategoryService) ListCategories(ctx context.Context, rootID *uint) ([]models.Category, error) {
+	categories, err := s.categoryRepo.List(ctx, rootID)
+	if err != nil && !errors.Is(err, repository.ErrCategoryNotFound) {
+		s.logger.Error("Failed to list categories", "error", err)
+	}
+	return categories, err
+}
+
+// UpdateCategory renames a category and moves it under a new parent
+func (s *CategoryService) UpdateCategory(ctx context.Context, id uint, input *models.Category) (*models.Category, error) {
+	category, err := s.categoryRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Name = input.Name
+	category.Slug = input.Slug
+	category.ParentID = input.ParentID
+	if err := normalizeCategory(category); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		if !isCategoryClientError(err) {
+			s.logger.Error("Failed to update category", "error", err)
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a category without subcategories or products
+func (s *CategoryService) DeleteCategory(ctx context.Context, id uint) error {
+	if err := s.categoryRepo.Delete(ctx, id); err != nil {
+		if !isCategoryClientError(err) {
+			s.logger.Error("Failed to delete category", "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// normalizeCategory trims the name and derives a slug from it if none is given
+func normalizeCategory(category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return ErrCategoryNameRequired
+	}
+
+	if category.Slug == "" {
+		category.Slug = strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(category.Name), "-"), "-")
+	}
+	if !slugPattern.MatchString(category.Slug) {
+		return ErrInvalidSlug
+	}
+	return nil
+}
+
+func isCategoryClientError(err error) bool {
+	return errors.Is(err, repository.ErrCategoryNotFound) ||
+		errors.Is(err, repository.ErrParentNotFound) ||
+		errors.Is(err, repository.ErrCategoryExists) ||
+		errors.Is(err, repository.ErrCategoryCycle) ||
+		errors.Is(err, repository.ErrCategoryInUse)
+}
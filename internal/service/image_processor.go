@@ -2,110 +2,248 @@ package service
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"image/png"
-	"net/http"
-	"path/filepath"
+	"io"
 	"product-management-system/internal/models"
 	"product-management-system/pkg/logger"
+	"product-management-system/pkg/utils"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
-	"github.com/nfnt/resize"
+	"golang.org/x/sync/errgroup"
 )
 
+// ErrForeignImageSource is returned for stored images that aren't one of
+// the product's own uploads
+var ErrForeignImageSource = errors.New("image source is not an upload of this product")
+
 type ImageProcessor struct {
-	s3Client *s3.S3
-	bucket   string
-	logger   *logger.Logger
+	store          BlobStore
+	fetcher        *utils.Fetcher
+	variants       []models.ImageVariantSpec
+	maxConcurrency int
+	logger         *logger.Logger
 }
 
-func (ip *ImageProcessor) ProcessImages(task *models.ImageProcessingTask) error {
-	var compressedImages []string
+// ProcessImages renders and uploads every configured variant of the task's
+// images, up to maxConcurrency images at a time, keeping the results in
+// source order. The first failure cancels the other images, since the
+// whole task is retried.
+func (ip *ImageProcessor) ProcessImages(ctx context.Context, task *models.ImageProcessingTask) error {
+	processedImages := make([]models.ProcessedImage, len(task.ImageURLs))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(ip.maxConcurrency)
+	for i, imageURL := range task.ImageURLs {
+		g.Go(func() error {
+			processedImage, err := ip.processImage(gctx, task.ProductID, imageURL)
+			if err != nil {
+				// Images cancelled after another one failed aren't worth a log line
+				if !errors.Is(err, context.Canceled) {
+					ip.logger.Error("Image processing failed", "url", imageURL, "error", err)
+				}
+				return err
+			}
+			processedImages[i] = *processedImage
+			return nil
+		})
+	}
 
-	for _, imageURL := range task.ImageURLs {
-		compressedImage, err := ip.compressAndUploadImage(imageURL)
-		if err != nil {
-			ip.logger.Error("Image processing failed", "url", imageURL, "error", err)
-			task.Status = "failed"
-			task.ErrorMessage = err.Error()
-			return err
-		}
-		compressedImages = append(compressedImages, compressedImage)
+	if err := g.Wait(); err != nil {
+		task.Status = models.ImageStatusFailed
+		task.ErrorMessage = err.Error()
+		return err
 	}
 
-	// Update product with compressed image URLs
-	// This would typically be done through a repository method
+	// The caller persists these onto the product
+	task.ProcessedImages = processedImages
+	task.Status = models.ImageStatusDone
+	task.ErrorMessage = ""
+	task.ProcessedAt = time.Now()
 	return nil
 }
 
-func (ip *ImageProcessor) compressAndUploadImage(imageURL string) (string, error) {
+// processImage downloads one source image and uploads all of its variants
+func (ip *ImageProcessor) processImage(ctx context.Context, productID uint, imageURL string) (*models.ProcessedImage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Download the image
-	resp, err := http.Get(imageURL)
+	data, err := ip.readSource(ctx, productID, imageURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to download image: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Decode the image
-	var img image.Image
-	var format string
-	switch resp.Header.Get("Content-Type") {
-	case "image/jpeg":
-		img, err = jpeg.Decode(resp.Body)
-		format = "jpeg"
-	case "image/png":
-		img, err = png.Decode(resp.Body)
-		format = "png"
-	default:
-		return "", fmt.Errorf("unsupported image format: %s", resp.Header.Get("Content-Type"))
+		return nil, fmt.Errorf("failed to download image: %w", err)
 	}
+
+	// Decode the image. The format is sniffed from the content since CDNs
+	// often serve images as application/octet-stream
+	img, format, err := utils.DecodeImage(bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return nil, err
 	}
 
-	// Resize the image
-	resizedImg := resize.Resize(800, 0, img, resize.Lanczos3)
+	// Turn phone photos upright before resizing. Variants are encoded from
+	// pixels alone, so EXIF, GPS and XMP metadata never reach the output
+	meta := utils.ReadImageMetadata(data)
+	img = utils.Orient(img, meta.Orientation)
+
+	processed := &models.ProcessedImage{
+		SourceURL:      imageURL,
+		OriginalWidth:  img.Bounds().Dx(),
+		OriginalHeight: img.Bounds().Dy(),
+		CapturedAt:     meta.CapturedAt,
+		CameraModel:    meta.CameraModel,
+	}
+	for _, spec := range ip.variants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		variant, err := ip.renderVariant(ctx, productID, img, format, spec)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %w", spec.Name, err)
+		}
+		processed.Variants = append(processed.Variants, *variant)
+	}
 
-	// Compress the image
-	var buf bytes.Buffer
-	switch format {
-	case "jpeg":
-		err = jpeg.Encode(&buf, resizedImg, &jpeg.Options{Quality: 75})
-	case "png":
-		err = png.Encode(&buf, resizedImg)
+	return processed, nil
+}
+
+// readSource reads a source image. Uploaded images are read from the blob
+// store directly since its URLs may be private or relative to the API;
+// anything else goes through the fetcher's address and size checks. Either
+// way, images whose header declares more pixels than we'll decode are
+// refused. Only the product's own confirmed uploads may be read from the
+// store.
+func (ip *ImageProcessor) readSource(ctx context.Context, productID uint, imageURL string) ([]byte, error) {
+	base := ip.store.URL("")
+	if !strings.HasPrefix(imageURL, base) {
+		return ip.fetcher.FetchImage(ctx, imageURL)
 	}
+
+	key := strings.TrimPrefix(imageURL, base)
+	if !strings.HasPrefix(key, uploadPrefix(productID)) ||
+		strings.HasPrefix(key, pendingUploadPrefix(productID)) || strings.Contains(key, "..") {
+		return nil, fmt.Errorf("%w: %s", ErrForeignImageSource, imageURL)
+	}
+
+	body, err := ip.store.Get(ctx, key)
 	if err != nil {
-		return "", fmt.Errorf("failed to compress image: %w", err)
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// FetchImage checks the header itself; uploads are checked here
+	if err := ip.fetcher.CheckImage(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// renderVariant resizes, encodes and uploads a single rendition
+func (ip *ImageProcessor) renderVariant(ctx context.Context, productID uint, img image.Image, sourceFormat string, spec models.ImageVariantSpec) (*models.ImageVariant, error) {
+	resized := utils.FitImage(img, spec.Width, spec.Height, spec.Fit)
+
+	format := spec.Format
+	if format == "" {
+		format = utils.OutputFormat(sourceFormat)
+	}
+	bounds := resized.Bounds()
+	if format == utils.FormatWebP && (bounds.Dx() > utils.MaxWebPDimension || bounds.Dy() > utils.MaxWebPDimension) {
+		format = utils.WebPFallbackFormat(sourceFormat)
+	}
+
+	var buf bytes.Buffer
+	if err := utils.EncodeImage(&buf, resized, format, spec.Quality); err != nil {
+		return nil, fmt.Errorf("failed to compress image: %w", err)
+	}
+
+	// Upload to the blob store. The key is derived from the output bytes,
+	// so a redelivered task overwrites its own objects instead of adding new ones
+	key := imageObjectKey(productID, spec.Name, format, buf.Bytes())
+	if err := ip.store.Put(ctx, key, bytes.NewReader(buf.Bytes()), "image/"+format); err != nil {
+		return nil, fmt.Errorf("failed to upload image: %w", err)
 	}
 
-	// Upload to S3
-	uploader := s3manager.NewUploaderWithClient(ip.s3Client)
-	key := fmt.Sprintf("compressed/%s", filepath.Base(imageURL))
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(ip.bucket),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(buf.Bytes()),
-	})
+	return &models.ImageVariant{
+		Name:   spec.Name,
+		URL:    ip.store.URL(key),
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Format: format,
+		Size:   buf.Len(),
+	}, nil
+}
+
+// CollectGarbage deletes a product's stored images that aren't in keepURLs.
+// Only objects last written before cutoff are removed, so uploads of a
+// newer task for the same product that is still running are left alone.
+// Pending uploads may still be confirmed, so they are only removed once
+// they are pendingUploadRetention older than cutoff.
+func (ip *ImageProcessor) CollectGarbage(ctx context.Context, productID uint, keepURLs []string, cutoff time.Time) (int, error) {
+	blobs, err := ip.store.List(ctx, productImagePrefix(productID))
 	if err != nil {
-		return "", fmt.Errorf("failed to upload image to S3: %w", err)
+		return 0, err
 	}
 
-	// Return the S3 URL of the compressed image
-	s3URL := fmt.Sprintf("https://%s.s3.amazonaws.com/%s", ip.bucket, key)
-	return s3URL, nil
+	pending := pendingUploadPrefix(productID)
+	deleted := 0
+	for _, blob := range blobs {
+		if strings.HasPrefix(blob.Key, pending) {
+			if blob.LastModified.Before(cutoff.Add(-pendingUploadRetention)) {
+				if err := ip.store.Delete(ctx, blob.Key); err != nil {
+					return deleted, err
+				}
+				deleted++
+			}
+			continue
+		}
+		if !blob.LastModified.Before(cutoff) || slices.Contains(keepURLs, ip.store.URL(blob.Key)) {
+			continue
+		}
+		if err := ip.store.Delete(ctx, blob.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
 }
 
-func NewImageProcessor(s3Client *s3.S3, s3Bucket string, appLogger *logger.Logger) *ImageProcessor {
+func productImagePrefix(productID uint) string {
+	return fmt.Sprintf("products/%d/", productID)
+}
+
+// imageObjectKey builds a collision-free key from the product, the variant
+// and a SHA-256 of the encoded bytes
+func imageObjectKey(productID uint, variant string, format string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s%s/%s.%s", productImagePrefix(productID), variant, hex.EncodeToString(sum[:]), format)
+}
+
+func NewImageProcessor(store BlobStore, fetcher *utils.Fetcher, variants []models.ImageVariantSpec, maxConcurrency int, appLogger *logger.Logger) *ImageProcessor {
+
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
 
 	return &ImageProcessor{
 
-		s3Client: s3Client,
+		store: store,
+
+		fetcher: fetcher,
+
+		variants: variants,
 
-		bucket: s3Bucket,
+		maxConcurrency: maxConcurrency,
 
 		logger: appLogger,
 	}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/pkg/logger"
+	"product-management-system/pkg/utils"
+
+	"go.uber.org/zap"
+)
+
+func testLogger() *logger.Logger {
+	return &logger.Logger{SugaredLogger: zap.NewNop().Sugar()}
+}
+
+func TestCollectGarbageKeepsPendingUploads(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore("")
+	processor := NewImageProcessor(store, nil, nil, 1, testLogger())
+
+	kept := imageObjectKey(7, "thumbnail", "webp", []byte("kept"))
+	stale := imageObjectKey(7, "thumbnail", "webp", []byte("stale"))
+	pending := pendingUploadKey(7, strings.Repeat("a", 32))
+	for _, key := range []string{kept, stale, pending} {
+		if err := store.Put(ctx, key, strings.NewReader(key), "image/webp"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := processor.CollectGarbage(ctx, 7, []string{store.URL(kept)}, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d objects, want 1", deleted)
+	}
+	assertBlobs(t, store, map[string]bool{kept: true, stale: false, pending: true})
+
+	// Abandoned uploads go once they are past the retention
+	deleted, err = processor.CollectGarbage(ctx, 7, []string{store.URL(kept)}, time.Now().Add(pendingUploadRetention+time.Second))
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d objects, want 1", deleted)
+	}
+	assertBlobs(t, store, map[string]bool{kept: true, pending: false})
+}
+
+func assertBlobs(t *testing.T, store BlobStore, want map[string]bool) {
+	t.Helper()
+	for key, exists := range want {
+		body, err := store.Get(context.Background(), key)
+		if err == nil {
+			body.Close()
+		}
+		if (err == nil) != exists {
+			t.Errorf("%s exists = %v, want %v", key, err == nil, exists)
+		}
+	}
+}
+
+func TestReadSourceOnlyReadsOwnUploads(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore("")
+	config := utils.DefaultFetcherConfig()
+	config.MaxPixels = 100
+	fetcher, err := utils.NewFetcher(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor := NewImageProcessor(store, fetcher, nil, 1, testLogger())
+
+	small, large := encodePNG(t, 10, 10), encodePNG(t, 11, 10)
+	own := uploadKey(7, "png", small)
+	oversized := uploadKey(7, "png", large)
+	other := uploadKey(8, "png", small)
+	processed := imageObjectKey(7, "thumbnail", "webp", []byte("processed"))
+	pending := pendingUploadKey(7, strings.Repeat("b", 32))
+	objects := map[string][]byte{own: small, oversized: large, other: small, processed: small, pending: small}
+	for key, data := range objects {
+		if err := store.Put(ctx, key, bytes.NewReader(data), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := processor.readSource(ctx, 7, store.URL(own))
+	if err != nil || !bytes.Equal(data, small) {
+		t.Errorf("readSource(own upload) = %d bytes, %v", len(data), err)
+	}
+	if _, err := processor.readSource(ctx, 7, store.URL(oversized)); !errors.Is(err, utils.ErrImageTooLarge) {
+		t.Errorf("readSource(oversized upload) error = %v, want %v", err, utils.ErrImageTooLarge)
+	}
+	for _, key := range []string{other, processed, pending, uploadPrefix(7) + "../../8/uploads/x.png"} {
+		if _, err := processor.readSource(ctx, 7, store.URL(key)); !errors.Is(err, ErrForeignImageSource) {
+			t.Errorf("readSource(%s) error = %v, want %v", key, err, ErrForeignImageSource)
+		}
+	}
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRenderVariantFallsBackForOversizeWebP(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore("")
+	processor := NewImageProcessor(store, nil, nil, 1, testLogger())
+
+	tests := []struct {
+		width        int
+		sourceFormat string
+		want         string
+	}{
+		{utils.MaxWebPDimension, utils.FormatJPEG, utils.FormatWebP},
+		{utils.MaxWebPDimension + 1, utils.FormatJPEG, utils.FormatJPEG},
+		{utils.MaxWebPDimension + 1, utils.FormatGIF, utils.FormatPNG},
+	}
+	for _, tt := range tests {
+		img := image.NewNRGBA(image.Rect(0, 0, tt.width, 1))
+		spec := models.ImageVariantSpec{Name: "original", Fit: utils.FitContain, Quality: 90, Format: utils.FormatWebP}
+		variant, err := processor.renderVariant(ctx, 7, img, tt.sourceFormat, spec)
+		if err != nil {
+			t.Fatalf("renderVariant(%dpx %s): %v", tt.width, tt.sourceFormat, err)
+		}
+		if variant.Format != tt.want || variant.Width != tt.width {
+			t.Errorf("renderVariant(%dpx %s) = %s %dpx, want %s", tt.width, tt.sourceFormat, variant.Format, variant.Width, tt.want)
+		}
+	}
+}
+
+func TestProcessImagesStopsAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore("")
+	fetcher, err := utils.NewFetcher(utils.DefaultFetcherConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	variants := []models.ImageVariantSpec{{Name: "thumbnail", Width: 8, Height: 8, Fit: utils.FitCover, Quality: 80, Format: utils.FormatPNG}}
+	processor := NewImageProcessor(store, fetcher, variants, 1, testLogger())
+
+	data := encodePNG(t, 16, 16)
+	own := uploadKey(7, "png", data)
+	foreign := uploadKey(8, "png", data)
+	for _, key := range []string{own, foreign} {
+		if err := store.Put(ctx, key, bytes.NewReader(data), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	task := &models.ImageProcessingTask{ProductID: 7, ImageURLs: []string{store.URL(own)}}
+	if err := processor.ProcessImages(ctx, task); err != nil {
+		t.Fatalf("ProcessImages: %v", err)
+	}
+	rendered, err := store.List(ctx, productImagePrefix(7)+"thumbnail/")
+	if err != nil || len(rendered) != 1 {
+		t.Fatalf("rendered %d thumbnails, %v; want 1", len(rendered), err)
+	}
+	if err := store.Delete(ctx, rendered[0].Key); err != nil {
+		t.Fatal(err)
+	}
+
+	// With one image at a time, the second image starts after the first
+	// failed and is cancelled before it renders anything
+	task = &models.ImageProcessingTask{ProductID: 7, ImageURLs: []string{store.URL(foreign), store.URL(own)}}
+	if err := processor.ProcessImages(ctx, task); !errors.Is(err, ErrForeignImageSource) {
+		t.Fatalf("ProcessImages error = %v, want ErrForeignImageSource", err)
+	}
+	if task.Status != models.ImageStatusFailed {
+		t.Errorf("task status = %q, want %q", task.Status, models.ImageStatusFailed)
+	}
+	if rendered, _ := store.List(ctx, productImagePrefix(7)+"thumbnail/"); len(rendered) != 0 {
+		t.Errorf("rendered %d thumbnails after the task failed, want 0", len(rendered))
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"product-management-system/internal/models"
+	"product-management-system/pkg/utils"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrUploadTooLarge       = errors.New("image upload is too large")
+	ErrUnsupportedImageType = errors.New("unsupported image type, expected JPEG, PNG, GIF or WebP")
+	ErrImageDimensions      = errors.New("image dimensions exceed the allowed maximum")
+	ErrUploadNotFound       = errors.New("upload not found")
+)
+
+// uploadContentTypes are the content types accepted for presigned uploads
+var uploadContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// pendingUploadRetention is how long an unconfirmed upload is kept, far
+// beyond any presigned URL's expiry
+const pendingUploadRetention = 24 * time.Hour
+
+// UploadLimits bounds the images clients may upload to a product
+type UploadLimits struct {
+	MaxBytes      int64
+	MaxWidth      int
+	MaxHeight     int
+	PresignExpiry time.Duration
+}
+
+// UploadSlot is a presigned location a client uploads one image to before
+// confirming it with ConfirmUpload
+type UploadSlot struct {
+	UploadID  string
+	URL       string
+	Method    string
+	Headers   map[string]string
+	ExpiresAt time.Time
+}
+
+// MaxUploadBytes is the largest image UploadProductImage accepts
+func (s *ProductService) MaxUploadBytes() int64 {
+	return s.uploadLimits.MaxBytes
+}
+
+// UploadProductImage validates and stores an image sent to the API and
+// queues it for processing
+func (s *ProductService) UploadProductImage(ctx context.Context, productID uint, body io.Reader) (*models.Product, error) {
+	product, err := s.findForWrite(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.readUpload(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.attachUpload(ctx, product, data)
+}
+
+// CreateUploadSlot returns a presigned URL the client can upload one image
+// to directly, bypassing the API
+func (s *ProductService) CreateUploadSlot(ctx context.Context, productID uint, contentType string) (*UploadSlot, error) {
+	product, err := s.findForWrite(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !uploadContentTypes[contentType] {
+		return nil, ErrUnsupportedImageType
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+	uploadID := hex.EncodeToString(id)
+
+	expiresAt := time.Now().Add(s.uploadLimits.PresignExpiry)
+	url, err := s.blobStore.PresignPut(ctx, pendingUploadKey(product.ID, uploadID), contentType, s.uploadLimits.PresignExpiry)
+	if err != nil {
+		if !errors.Is(err, ErrPresignNotSupported) {
+			s.logger.Error("Failed to presign upload", "error", err, "productID", product.ID)
+		}
+		return nil, err
+	}
+
+	return &UploadSlot{
+		UploadID:  uploadID,
+		URL:       url,
+		Method:    "PUT",
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ConfirmUpload validates an image uploaded to a slot and queues it for
+// processing. The pending object is removed whether or not it is accepted.
+func (s *ProductService) ConfirmUpload(ctx context.Context, productID uint, uploadID string) (*models.Product, error) {
+	product, err := s.findForWrite(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !uploadIDPattern.MatchString(uploadID) {
+		return nil, ErrUploadNotFound
+	}
+
+	key := pendingUploadKey(product.ID, uploadID)
+	body, err := s.blobStore.Get(ctx, key)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.readUpload(body)
+	body.Close()
+	if err == nil {
+		product, err = s.attachUpload(ctx, product, data)
+	}
+
+	if delErr := s.blobStore.Delete(ctx, key); delErr != nil {
+		s.logger.Warn("Failed to delete pending upload", "error", delErr, "key", key)
+	}
+	return product, err
+}
+
+// attachUpload stores a validated image, adds it to the product and
+// enqueues processing for that image alone. Sources are stored at a public
+// URL, so their metadata is stripped first; GPS coordinates in particular
+// must not outlive the upload.
+func (s *ProductService) attachUpload(ctx context.Context, product *models.Product, data []byte) (*models.Product, error) {
+	format, err := s.validateUpload(data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = utils.StripMetadata(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImageType, err)
+	}
+
+	key := uploadKey(product.ID, format, data)
+	if err := s.blobStore.Put(ctx, key, bytes.NewReader(data), "image/"+format); err != nil {
+		s.logger.Error("Failed to store uploaded image", "error", err, "productID", product.ID)
+		return nil, err
+	}
+	imageURL := s.blobStore.URL(key)
+
+	// Re-uploading an image the product already has is a no-op; otherwise
+	// processing of the image alone is queued with it
+	if _, err := s.productRepo.AppendProductImage(ctx, product.ID, imageURL); err != nil {
+		s.logger.Error("Failed to add uploaded image", "error", err, "productID", product.ID)
+		return nil, err
+	}
+
+	return s.productRepo.FindByID(ctx, product.ID)
+}
+
+// readUpload reads an upload body, failing once it exceeds MaxBytes
+func (s *ProductService) readUpload(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, s.uploadLimits.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > s.uploadLimits.MaxBytes {
+		return nil, ErrUploadTooLarge
+	}
+	return data, nil
+}
+
+// validateUpload checks the type and dimensions of an image without
+// decoding its pixels and returns its format
+func (s *ProductService) validateUpload(data []byte) (string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedImageType
+	}
+
+	if config.Width > s.uploadLimits.MaxWidth || config.Height > s.uploadLimits.MaxHeight {
+		return "", fmt.Errorf("%w: %dx%d is larger than %dx%d", ErrImageDimensions,
+			config.Width, config.Height, s.uploadLimits.MaxWidth, s.uploadLimits.MaxHeight)
+	}
+
+	// Only accept formats the worker can process, whatever else is registered
+	switch format {
+	case utils.FormatJPEG, utils.FormatPNG, utils.FormatGIF, utils.FormatWebP:
+		return format, nil
+	default:
+		return "", ErrUnsupportedImageType
+	}
+}
+
+// uploadKey stores uploaded sources next to the product's processed images
+// so they are cleaned up with them
+func uploadKey(productID uint, format string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s%s.%s", uploadPrefix(productID), hex.EncodeToString(sum[:]), format)
+}
+
+// uploadPrefix holds a product's uploaded sources, the only stored objects
+// the worker reads
+func uploadPrefix(productID uint) string {
+	return productImagePrefix(productID) + "uploads/"
+}
+
+func pendingUploadKey(productID uint, uploadID string) string {
+	return pendingUploadPrefix(productID) + uploadID
+}
+
+// pendingUploadPrefix holds uploads awaiting ConfirmUpload, which garbage
+// collection leaves alone
+func pendingUploadPrefix(productID uint) string {
+	return uploadPrefix(productID) + "pending/"
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/internal/queue"
+	"product-management-system/internal/repository"
+	"product-management-system/pkg/logger"
+)
+
+// ImageWorker processes image tasks delivered by a TaskQueue. Several
+// workers run concurrently on the same delivery channel.
+type ImageWorker struct {
+	productRepo    *repository.ProductRepository
+	imageProcessor *ImageProcessor
+	logger         *logger.Logger
+}
+
+func NewImageWorker(productRepo *repository.ProductRepository, imageProcessor *ImageProcessor, logger *logger.Logger) *ImageWorker {
+	return &ImageWorker{
+		productRepo:    productRepo,
+		imageProcessor: imageProcessor,
+		logger:         logger,
+	}
+}
+
+// ImageWorkerSettings bounds how many tasks are processed and held at once
+// and how long shutdown waits for them
+type ImageWorkerSettings struct {
+	Concurrency     int
+	Prefetch        int
+	ShutdownTimeout time.Duration
+}
+
+// RunImageWorkers consumes taskQueue with Concurrency workers until ctx is
+// cancelled, then waits up to ShutdownTimeout for in-flight tasks. Tasks
+// abandoned at the deadline are delivered again by the queue.
+func RunImageWorkers(ctx context.Context, taskQueue queue.TaskQueue, worker *ImageWorker, settings ImageWorkerSettings) error {
+	deliveries, err := taskQueue.Consume(ctx, settings.Prefetch)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < settings.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.Run(deliveries)
+		}()
+	}
+
+	// Closed once every worker has drained the delivery channel
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		// The queue stops delivering only once ctx is cancelled
+		return nil
+	case <-ctx.Done():
+	}
+
+	worker.logger.Info("Waiting for in-flight image tasks", "timeout", settings.ShutdownTimeout)
+	select {
+	case <-drained:
+		worker.logger.Info("All in-flight tasks finished")
+	case <-time.After(settings.ShutdownTimeout):
+		worker.logger.Warn("Shutdown deadline exceeded, abandoning in-flight tasks")
+	}
+	return nil
+}
+
+// Run handles deliveries until the channel is closed
+func (w *ImageWorker) Run(deliveries <-chan queue.Delivery) {
+	for d := range deliveries {
+		w.handle(d)
+	}
+}
+
+func (w *ImageWorker) handle(d queue.Delivery) {
+	// Parse message to ImageProcessingTask
+	task := &models.ImageProcessingTask{}
+	if err := json.Unmarshal(d.Body(), task); err != nil {
+		w.logger.Error("Failed to parse message", "error", err)
+		// Malformed payloads can never succeed, so skip the retries
+		if err := d.DeadLetter(err); err != nil {
+			w.logger.Error("Failed to dead-letter message", "error", err)
+			d.Nack()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	if err := w.productRepo.UpdateImageStatus(ctx, task.ProductID, models.ImageStatusProcessing, ""); err != nil {
+		w.logger.Warn("Failed to mark images as processing", "error", err, "productID", task.ProductID)
+	}
+
+	// Process images
+	startedAt := time.Now()
+	if err := w.imageProcessor.ProcessImages(ctx, task); err != nil {
+		w.logger.Error("Image processing failed", "error", err, "productID", task.ProductID, "attempt", d.Attempts()+1)
+		w.retry(ctx, d, task, err)
+		return
+	}
+
+	// Merge the results into the product. Results for images removed while
+	// the task ran are dropped; a newer task's garbage collection removes
+	// whatever this one uploaded for them
+	product, err := w.productRepo.MergeProcessedImages(ctx, task.ProductID, task.ProcessedImages, task.ProcessedAt)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		w.logger.Info("Product deleted during image processing", "productID", task.ProductID)
+		w.collectGarbage(ctx, task.ProductID, nil, startedAt)
+		w.ack(d, task.ProductID)
+		return
+	}
+	if err != nil {
+		w.logger.Error("Failed to update product images", "error", err, "productID", task.ProductID)
+		w.retry(ctx, d, task, err)
+		return
+	}
+
+	// Remove images left over from the product's previous sources. Uploaded
+	// sources live under the same prefix, so keep those too
+	if !task.Append {
+		keepURLs := slices.Clone(product.ProductImages)
+		for _, image := range product.ProcessedImages {
+			keepURLs = append(keepURLs, image.URLs()...)
+		}
+		w.collectGarbage(ctx, task.ProductID, keepURLs, startedAt)
+	}
+
+	// Acknowledge message
+	w.ack(d, task.ProductID)
+}
+
+// ack settles a finished task. If that fails, e.g. because its lease ran
+// out, the task is delivered again and redone, which is harmless.
+func (w *ImageWorker) ack(d queue.Delivery, productID uint) {
+	if err := d.Ack(); err != nil {
+		w.logger.Warn("Failed to acknowledge task", "error", err, "productID", productID)
+	}
+}
+
+func (w *ImageWorker) collectGarbage(ctx context.Context, productID uint, keepURLs []string, cutoff time.Time) {
+	deleted, err := w.imageProcessor.CollectGarbage(ctx, productID, keepURLs, cutoff)
+	if err != nil {
+		// Not fatal: the next successful task for the product retries it
+		w.logger.Warn("Failed to garbage-collect product images", "error", err, "productID", productID)
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("Garbage-collected product images", "productID", productID, "deleted", deleted)
+	}
+}
+
+// retry schedules a failed task for retry or dead-letters it, and records
+// the outcome on the product
+func (w *ImageWorker) retry(ctx context.Context, d queue.Delivery, task *models.ImageProcessingTask, cause error) {
+	deadLettered, err := d.Retry(cause)
+	if err != nil {
+		w.logger.Error("Failed to schedule retry", "error", err, "productID", task.ProductID)
+		d.Nack() // Requeue
+		return
+	}
+
+	// Keep the product pending while retries remain
+	status := models.ImageStatusPending
+	if deadLettered {
+		status = models.ImageStatusFailed
+		w.logger.Error("Image processing task dead-lettered", "productID", task.ProductID, "error", cause)
+	}
+	if err := w.productRepo.UpdateImageStatus(ctx, task.ProductID, status, cause.Error()); err != nil {
+		w.logger.Warn("Failed to record image processing failure", "error", err, "productID", task.ProductID)
+	}
+}
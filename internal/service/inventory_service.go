@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"product-management-system/pkg/logger"
+	"time"
+)
+
+// expiryBatchSize is how many expired reservations are released per
+// transaction
+const expiryBatchSize = 100
+
+var ErrInvalidQuantity = errors.New("quantity must be positive")
+
+type InventoryService struct {
+	productRepo    *repository.ProductRepository
+	productService *ProductService
+	reservationTTL time.Duration
+	logger         *logger.Logger
+}
+
+func NewInventoryService(
+	productRepo *repository.ProductRepository,
+	productService *ProductService,
+	reservationTTL time.Duration,
+	logger *logger.Logger,
+) *InventoryService {
+	return &InventoryService{
+		productRepo:    productRepo,
+		productService: productService,
+		reservationTTL: reservationTTL,
+		logger:         logger,
+	}
+}
+
+// Reserve holds quantity units of a visible product, or of one of its
+// variants, for the caller until the reservation TTL runs out
+func (s *InventoryService) Reserve(ctx context.Context, productID uint, variantID *uint, quantity int) (*models.StockReservation, error) {
+	if quantity < 1 {
+		return nil, ErrInvalidQuantity
+	}
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil, ErrForbidden
+	}
+	if _, err := s.productService.FindProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	reservation := &models.StockReservation{
+		UserID:    claims.UserID(),
+		ProductID: productID,
+		VariantID: variantID,
+		Quantity:  quantity,
+		ExpiresAt: time.Now().Add(s.reservationTTL),
+	}
+	if err := s.productRepo.Reserve(ctx, reservation); err != nil {
+		if !isInventoryClientError(err) {
+			s.logger.Error("Failed to reserve stock", "productID", productID, "error", err)
+		}
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// FindReservation returns a reservation the caller made or holds on one
+// of their products. Admins can see every reservation.
+func (s *InventoryService) FindReservation(ctx context.Context, id uint) (*models.StockReservation, error) {
+	reservation, err := s.productRepo.FindReservation(ctx, id)
+	if err != nil {
+		if !errors.Is(err, repository.ErrReservationNotFound) {
+			s.logger.Error("Failed to find reservation", "reservationID", id, "error", err)
+		}
+		return nil, err
+	}
+
+	// Other users' reservations look missing rather than forbidden
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil, repository.ErrReservationNotFound
+	}
+	if claims.UserID() == reservation.UserID {
+		return reservation, nil
+	}
+	seller, err := s.isSeller(ctx, claims, reservation)
+	if err != nil {
+		return nil, err
+	}
+	if !seller {
+		return nil, repository.ErrReservationNotFound
+	}
+	return reservation, nil
+}
+
+// CommitReservation takes a reservation's units out of stock once the
+// order is fulfilled. Only the product's owner and admins may commit, since
+// committing permanently reduces the seller's stock; buyers can only hold
+// and release units.
+func (s *InventoryService) CommitReservation(ctx context.Context, id uint) (*models.StockReservation, error) {
+	reservation, err := s.FindReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	claims, _ := ClaimsFromContext(ctx)
+	seller, err := s.isSeller(ctx, claims, reservation)
+	if err != nil {
+		return nil, err
+	}
+	if !seller {
+		return nil, ErrForbidden
+	}
+
+	reservation, err = s.productRepo.CommitReservation(ctx, id, time.Now())
+	if err != nil {
+		if !isInventoryClientError(err) {
+			s.logger.Error("Failed to commit reservation", "reservationID", id, "error", err)
+		}
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation returns a reservation's units to stock. The buyer who
+// made it and the product's owner may release it.
+func (s *InventoryService) ReleaseReservation(ctx context.Context, id uint) (*models.StockReservation, error) {
+	if _, err := s.FindReservation(ctx, id); err != nil {
+		return nil, err
+	}
+
+	reservation, err := s.productRepo.ReleaseReservation(ctx, id)
+	if err != nil {
+		if !isInventoryClientError(err) {
+			s.logger.Error("Failed to release reservation", "reservationID", id, "error", err)
+		}
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// RunReservationExpiry releases expired reservations every interval until
+// ctx is cancelled
+func (s *InventoryService) RunReservationExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		expired, err := s.ExpireReservations(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to expire reservations", "error", err)
+		}
+		if expired > 0 {
+			s.logger.Info("Expired stock reservations", "count", expired)
+		}
+	}
+}
+
+// ExpireReservations releases every reservation that has expired, in
+// batches, and returns how many it released
+func (s *InventoryService) ExpireReservations(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		expired, err := s.productRepo.ExpireReservations(ctx, time.Now(), expiryBatchSize)
+		total += expired
+		if err != nil || expired < expiryBatchSize {
+			return total, err
+		}
+	}
+}
+
+// isSeller reports whether the caller owns the reserved product or is an
+// admin. Only admins manage reservations of deleted products.
+func (s *InventoryService) isSeller(ctx context.Context, claims *TokenClaims, reservation *models.StockReservation) (bool, error) {
+	if claims == nil {
+		return false, nil
+	}
+	if claims.Role == models.RoleAdmin {
+		return true, nil
+	}
+
+	product, err := s.productRepo.FindByID(ctx, reservation.ProductID)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to find reserved product", "productID", reservation.ProductID, "error", err)
+		return false, err
+	}
+	return CanModifyProduct(claims, product), nil
+}
+
+func isInventoryClientError(err error) bool {
+	return errors.Is(err, repository.ErrProductNotFound) ||
+		errors.Is(err, repository.ErrVariantNotFound) ||
+		errors.Is(err, repository.ErrVariantRequired) ||
+		errors.Is(err, repository.ErrInsufficientStock) ||
+		errors.Is(err, repository.ErrReservationNotFound) ||
+		errors.Is(err, repository.ErrReservationNotActive) ||
+		errors.Is(err, repository.ErrReservationExpired)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-management-system/internal/models"
+	"product-management-system/internal/queue"
+	"product-management-system/internal/repository"
+	"product-management-system/pkg/logger"
+	"time"
+)
+
+// purgeBatchSize bounds how many sent messages one delete removes
+const purgeBatchSize = 1000
+
+// OutboxSettings configures the outbox relay
+type OutboxSettings struct {
+	// Interval is how often the outbox is polled
+	Interval  time.Duration
+	BatchSize int
+	// Lease is how long claimed messages are hidden from other relays
+	// while they are published
+	Lease time.Duration
+	// MaxBackoff caps the delay between attempts to publish a message,
+	// which doubles from Interval
+	MaxBackoff time.Duration
+	// Retention is how long sent messages are kept
+	Retention time.Duration
+}
+
+// OutboxRelay publishes outbox messages to the task queue. A message is
+// marked sent only after it is published, so delivery is at least once: a
+// relay that dies between the two publishes it again. Any number of relays
+// can run against the same database.
+type OutboxRelay struct {
+	outboxRepo *repository.OutboxRepository
+	taskQueue  queue.TaskQueue
+	settings   OutboxSettings
+	logger     *logger.Logger
+}
+
+func NewOutboxRelay(
+	outboxRepo *repository.OutboxRepository,
+	taskQueue queue.TaskQueue,
+	settings OutboxSettings,
+	logger *logger.Logger,
+) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		taskQueue:  taskQueue,
+		settings:   settings,
+		logger:     logger,
+	}
+}
+
+// Run relays due messages every interval and purges old sent ones until
+// ctx is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The queue logs its own reconnection attempts
+		sent, err := r.Relay(ctx)
+		if err != nil && ctx.Err() == nil && !errors.Is(err, queue.ErrNotConnected) {
+			r.logger.Error("Failed to relay outbox messages", "error", err)
+		}
+		if sent > 0 {
+			r.logger.Info("Relayed outbox messages", "count", sent)
+		}
+
+		if _, err := r.outboxRepo.PurgeSent(ctx, time.Now().Add(-r.settings.Retention), purgeBatchSize); err != nil && ctx.Err() == nil {
+			r.logger.Error("Failed to purge sent outbox messages", "error", err)
+		}
+	}
+}
+
+// Relay publishes every due message, in batches, and returns how many it
+// published. Messages that fail to publish are retried with backoff.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		messages, err := r.outboxRepo.Claim(ctx, time.Now(), r.settings.Lease, r.settings.BatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		for i := range messages {
+			message := &messages[i]
+			if err := r.publish(ctx, message); err != nil {
+				r.logger.Warn("Failed to publish outbox message",
+					"id", message.ID, "topic", message.Topic, "attempts", message.Attempts+1, "error", err)
+				retryAt := time.Now().Add(r.backoff(message.Attempts + 1))
+				if err := r.outboxRepo.MarkFailed(ctx, message.ID, err, retryAt); err != nil {
+					return total, err
+				}
+				// The rest of the batch would fail the same way; it is
+				// retried once its lease runs out
+				if errors.Is(err, queue.ErrNotConnected) {
+					return total, err
+				}
+				continue
+			}
+
+			// If this fails the message is published again once its
+			// lease runs out
+			if err := r.outboxRepo.MarkSent(ctx, message.ID, time.Now()); err != nil {
+				return total, err
+			}
+			total++
+		}
+
+		if len(messages) < r.settings.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, message *models.OutboxMessage) error {
+	switch message.Topic {
+	case models.OutboxTopicImageProcessing:
+		return r.taskQueue.Publish(ctx, message.Payload, outboxMessageID(message.ID))
+	default:
+		return fmt.Errorf("unknown outbox topic %q", message.Topic)
+	}
+}
+
+// backoff returns the delay before the given retry (1-based)
+func (r *OutboxRelay) backoff(retry int) time.Duration {
+	policy := queue.RetryPolicy{BaseDelay: r.settings.Interval, MaxDelay: r.settings.MaxBackoff}
+	return policy.Delay(retry)
+}
+
+// outboxMessageID is the AMQP message ID of an outbox message, the same
+// on every attempt
+func outboxMessageID(id uint) string {
+	return fmt.Sprintf("outbox-%d", id)
+}
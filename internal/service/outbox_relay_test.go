@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"product-management-system/internal/models"
+	"product-management-system/internal/queue"
+	"product-management-system/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// recordingQueue records published messages and fails those whose
+// message ID is in fail
+type recordingQueue struct {
+	queue.TaskQueue
+
+	mu        sync.Mutex
+	published []string
+	fail      map[string]error
+}
+
+func (q *recordingQueue) Publish(ctx context.Context, body []byte, messageID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.fail[messageID]; err != nil {
+		return err
+	}
+	q.published = append(q.published, messageID)
+	return nil
+}
+
+// enqueueTestMessages adds n due messages of topic to the outbox
+func enqueueTestMessages(t *testing.T, db *gorm.DB, topic string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		message := &models.OutboxMessage{Topic: topic, Payload: []byte(`{}`), AvailableAt: time.Now().Add(-time.Second)}
+		if err := db.Create(message).Error; err != nil {
+			t.Fatalf("failed to enqueue outbox message: %v", err)
+		}
+	}
+}
+
+func testOutboxSettings() OutboxSettings {
+	return OutboxSettings{
+		Interval:   time.Second,
+		BatchSize:  2,
+		Lease:      time.Minute,
+		MaxBackoff: time.Minute,
+		Retention:  time.Hour,
+	}
+}
+
+func TestOutboxRelayBackoff(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, OutboxSettings{Interval: time.Second, MaxBackoff: 10 * time.Second}, testLogger())
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range want {
+		if got := relay.backoff(i + 1); got != delay {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+	if got := outboxMessageID(42); got != "outbox-42" {
+		t.Errorf("outboxMessageID(42) = %q", got)
+	}
+}
+
+func TestOutboxRelayPublishesInOrder(t *testing.T) {
+	db := openTestDB(t, &models.OutboxMessage{})
+	enqueueTestMessages(t, db, models.OutboxTopicImageProcessing, 5)
+
+	taskQueue := &recordingQueue{}
+	relay := NewOutboxRelay(repository.NewOutboxRepository(db), taskQueue, testOutboxSettings(), testLogger())
+
+	sent, err := relay.Relay(context.Background())
+	if err != nil || sent != 5 {
+		t.Fatalf("Relay = %d, %v; want 5", sent, err)
+	}
+	for i, id := range taskQueue.published {
+		if want := outboxMessageID(uint(i + 1)); id != want {
+			t.Errorf("published[%d] = %q, want %q", i, id, want)
+		}
+	}
+
+	var unsent int64
+	db.Model(&models.OutboxMessage{}).Where("sent_at IS NULL").Count(&unsent)
+	if unsent != 0 {
+		t.Errorf("%d messages left unsent", unsent)
+	}
+
+	// Sent messages are not published again
+	if sent, err := relay.Relay(context.Background()); err != nil || sent != 0 {
+		t.Fatalf("second Relay = %d, %v; want 0", sent, err)
+	}
+	if len(taskQueue.published) != 5 {
+		t.Errorf("published %d messages, want 5", len(taskQueue.published))
+	}
+}
+
+func TestOutboxRelayBacksOffFailedMessages(t *testing.T) {
+	db := openTestDB(t, &models.OutboxMessage{})
+	enqueueTestMessages(t, db, models.OutboxTopicImageProcessing, 3)
+	enqueueTestMessages(t, db, "unknown", 1)
+
+	taskQueue := &recordingQueue{fail: map[string]error{outboxMessageID(2): errors.New("broker rejected message")}}
+	relay := NewOutboxRelay(repository.NewOutboxRepository(db), taskQueue, testOutboxSettings(), testLogger())
+
+	before := time.Now()
+	sent, err := relay.Relay(context.Background())
+	if err != nil || sent != 2 {
+		t.Fatalf("Relay = %d, %v; want 2", sent, err)
+	}
+
+	for _, id := range []uint{2, 4} {
+		var message models.OutboxMessage
+		if err := db.First(&message, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if message.SentAt != nil || message.Attempts != 1 || message.LastError == "" {
+			t.Errorf("message %d: sent %v, attempts %d, error %q", id, message.SentAt, message.Attempts, message.LastError)
+		}
+		if message.AvailableAt.Before(before.Add(time.Second)) {
+			t.Errorf("message %d retries at %v, want after the first backoff", id, message.AvailableAt)
+		}
+	}
+
+	// Failed messages aren't due yet
+	if sent, err := relay.Relay(context.Background()); err != nil || sent != 0 {
+		t.Fatalf("second Relay = %d, %v; want 0", sent, err)
+	}
+}
+
+func TestOutboxRelayStopsWhenDisconnected(t *testing.T) {
+	db := openTestDB(t, &models.OutboxMessage{})
+	enqueueTestMessages(t, db, models.OutboxTopicImageProcessing, 2)
+
+	fail := map[string]error{outboxMessageID(1): queue.ErrNotConnected, outboxMessageID(2): queue.ErrNotConnected}
+	taskQueue := &recordingQueue{fail: fail}
+	relay := NewOutboxRelay(repository.NewOutboxRepository(db), taskQueue, testOutboxSettings(), testLogger())
+
+	if _, err := relay.Relay(context.Background()); !errors.Is(err, queue.ErrNotConnected) {
+		t.Fatalf("Relay error = %v, want ErrNotConnected", err)
+	}
+
+	// The second message stays leased and untried
+	var message models.OutboxMessage
+	if err := db.First(&message, 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	if message.Attempts != 0 || message.SentAt != nil {
+		t.Errorf("message 2: attempts %d, sent %v", message.Attempts, message.SentAt)
+	}
+}
+
+func TestConcurrentOutboxRelays(t *testing.T) {
+	db := openTestDB(t, &models.OutboxMessage{})
+	enqueueTestMessages(t, db, models.OutboxTopicImageProcessing, 20)
+
+	taskQueue := &recordingQueue{}
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		relay := NewOutboxRelay(repository.NewOutboxRepository(db), taskQueue, testOutboxSettings(), testLogger())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := relay.Relay(context.Background())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Relay: %v", err)
+		}
+	}
+
+	// Leases keep relays off each other's messages, so each is published once
+	seen := map[string]bool{}
+	for _, id := range taskQueue.published {
+		if seen[id] {
+			t.Errorf("%s published twice", id)
+		}
+		seen[id] = true
+	}
+	for i := 1; i <= 20; i++ {
+		if id := outboxMessageID(uint(i)); !seen[id] {
+			t.Errorf("%s was not published", id)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on product tags and attributes
+const (
+	maxTags         = 32
+	maxTagLength    = 50
+	maxAttributes   = 50
+	maxAttributeLen = 256
+)
+
+var (
+	ErrInvalidCategory  = errors.New("category does not exist")
+	ErrInvalidTags      = fmt.Errorf("at most %d tags of up to %d characters are allowed", maxTags, maxTagLength)
+	ErrInvalidAttribute = errors.New("invalid attribute")
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// normalizeClassification validates a product's category, tags and
+// attributes. Tags are trimmed, lowercased and deduplicated.
+func (s *ProductService) normalizeClassification(ctx context.Context, product *models.Product) error {
+	if product.CategoryID != nil {
+		if _, err := s.categoryRepo.FindByID(ctx, *product.CategoryID); err != nil {
+			if errors.Is(err, repository.ErrCategoryNotFound) {
+				return ErrInvalidCategory
+			}
+			return err
+		}
+	}
+
+	tags, err := normalizeTags(product.Tags)
+	if err != nil {
+		return err
+	}
+	product.Tags = tags
+
+	return validateAttributes(product.Attributes)
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, ErrInvalidTags
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxTags {
+		return nil, ErrInvalidTags
+	}
+	return normalized, nil
+}
+
+// validateAttributes accepts string, number and boolean values under keys
+// made of letters, digits, underscores and hyphens
+func validateAttributes(attributes models.Attributes) error {
+	if len(attributes) > maxAttributes {
+		return fmt.Errorf("%w: at most %d attributes are allowed", ErrInvalidAttribute, maxAttributes)
+	}
+
+	for key, value := range attributes {
+		if !attributeKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: key %q may only contain letters, digits, underscores and hyphens", ErrInvalidAttribute, key)
+		}
+
+		switch v := value.(type) {
+		case string:
+			if utf8.RuneCountInString(v) > maxAttributeLen {
+				return fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidAttribute, key, maxAttributeLen)
+			}
+		case float64, bool:
+		default:
+			return fmt.Errorf("%w: %s must be a string, number or boolean", ErrInvalidAttribute, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"product-management-system/internal/models"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tooMany := make([]string, maxTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+	duplicated := append(slices.Clone(tooMany[:maxTags]), "TAG0", " tag1 ")
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+		err  error
+	}{
+		{"nil", nil, nil, nil},
+		{"lowercased and trimmed", []string{" Summer ", "SALE"}, []string{"summer", "sale"}, nil},
+		{"duplicates dropped in order", []string{"b", "a", "B", " a"}, []string{"b", "a"}, nil},
+		{"empty dropped", []string{"", "  ", "x"}, []string{"x"}, nil},
+		{"longest tag", []string{strings.Repeat("a", maxTagLength)}, []string{strings.Repeat("a", maxTagLength)}, nil},
+		{"multibyte tag counts characters", []string{strings.Repeat("é", maxTagLength)}, []string{strings.Repeat("é", maxTagLength)}, nil},
+		{"tag too long", []string{strings.Repeat("a", maxTagLength+1)}, nil, ErrInvalidTags},
+		{"too many tags", tooMany, nil, ErrInvalidTags},
+		{"duplicates don't count toward the limit", duplicated, tooMany[:maxTags], nil},
+	}
+	for _, tt := range tests {
+		got, err := normalizeTags(tt.tags)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: tags = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	tooMany := models.Attributes{}
+	for i := 0; i <= maxAttributes; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "value"
+	}
+
+	tests := []struct {
+		name       string
+		attributes models.Attributes
+		valid      bool
+	}{
+		{"nil", nil, true},
+		{"string, number and boolean", models.Attributes{"color": "red", "weight_kg": 1.5, "in-box": true}, true},
+		{"longest value", models.Attributes{"a": strings.Repeat("x", maxAttributeLen)}, true},
+		{"multibyte value counts characters", models.Attributes{"a": strings.Repeat("ü", maxAttributeLen)}, true},
+		{"value too long", models.Attributes{"a": strings.Repeat("x", maxAttributeLen+1)}, false},
+		{"longest key", models.Attributes{strings.Repeat("k", 64): "v"}, true},
+		{"key too long", models.Attributes{strings.Repeat("k", 65): "v"}, false},
+		{"empty key", models.Attributes{"": "v"}, false},
+		{"key with space", models.Attributes{"a b": "v"}, false},
+		{"key with dot", models.Attributes{"a.b": "v"}, false},
+		{"key with quote", models.Attributes{`a"b`: "v"}, false},
+		{"null value", models.Attributes{"a": nil}, false},
+		{"object value", models.Attributes{"a": map[string]interface{}{"b": "c"}}, false},
+		{"array value", models.Attributes{"a": []interface{}{"b"}}, false},
+		{"too many", tooMany, false},
+	}
+	for _, tt := range tests {
+		err := validateAttributes(tt.attributes)
+		if tt.valid && err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidAttribute) {
+			t.Errorf("%s: error = %v, want ErrInvalidAttribute", tt.name, err)
+		}
+	}
+}
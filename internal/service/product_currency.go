@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"product-management-system/pkg/money"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("currency has no conversion rate configured")
+	ErrInvalidPrice        = errors.New("price must not be negative")
+)
+
+// CurrencySettings configures the currencies products can be priced and
+// listed in
+type CurrencySettings struct {
+	// Default is the currency of products created without one, and of
+	// price filters and facets when no currency is requested
+	Default  string
+	Rates    *money.Rates
+	Rounding money.Rounding
+}
+
+// ConvertPrices rewrites the prices of products into currency for display.
+// A product's price list entry in currency is used as is; other prices are
+// converted and rounded to whole minor units with rounding, or the
+// configured mode if it is empty.
+func (s *ProductService) ConvertPrices(ctx context.Context, products []models.Product, currency string, rounding money.Rounding) error {
+	currency, err := s.supportedCurrency(currency)
+	if err != nil {
+		return err
+	}
+	if rounding == "" {
+		rounding = s.currency.Rounding
+	}
+
+	ids := make([]uint, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+	listed, err := s.productRepo.PriceListAmounts(ctx, ids, currency)
+	if err != nil {
+		s.logger.Error("Failed to load price lists", "currency", currency, "error", err)
+		return err
+	}
+
+	for i := range products {
+		product := &products[i]
+		from := product.Price.Currency
+
+		if amount, ok := listed[product.ID]; ok {
+			product.Price = money.Money{Amount: amount, Currency: currency}
+		} else if product.Price, err = s.currency.Rates.Convert(product.Price, currency, rounding); err != nil {
+			return fmt.Errorf("convert price of product %d: %w", product.ID, err)
+		}
+
+		for _, amount := range []*int64{product.PriceMinAmount, product.PriceMaxAmount} {
+			if amount == nil {
+				continue
+			}
+			converted, err := s.currency.Rates.Convert(money.Money{Amount: *amount, Currency: from}, currency, rounding)
+			if err != nil {
+				return fmt.Errorf("convert price range of product %d: %w", product.ID, err)
+			}
+			*amount = converted.Amount
+		}
+	}
+	return nil
+}
+
+// ListPriceList returns the per-currency prices of a product the caller
+// can see
+func (s *ProductService) ListPriceList(ctx context.Context, productID uint) ([]models.PriceListEntry, error) {
+	if _, err := s.FindProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.productRepo.ListPriceList(ctx, productID)
+	if err != nil {
+		s.logger.Error("Failed to list price list", "productID", productID, "error", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SetListPrice sets the price of a product in one currency, used instead
+// of converting its own price
+func (s *ProductService) SetListPrice(ctx context.Context, productID uint, price money.Money) (*models.PriceListEntry, error) {
+	if _, err := s.findForWrite(ctx, productID); err != nil {
+		return nil, err
+	}
+	currency, err := s.supportedCurrency(price.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if price.Amount < 0 {
+		return nil, ErrInvalidPrice
+	}
+
+	entry := &models.PriceListEntry{ProductID: productID, Currency: currency, Amount: price.Amount}
+	if err := s.productRepo.SetPriceListEntry(ctx, entry); err != nil {
+		if !errors.Is(err, repository.ErrProductNotFound) {
+			s.logger.Error("Failed to set list price", "productID", productID, "error", err)
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *ProductService) DeleteListPrice(ctx context.Context, productID uint, currency string) error {
+	if _, err := s.findForWrite(ctx, productID); err != nil {
+		return err
+	}
+	currency, err := money.NormalizeCurrency(currency)
+	if err != nil {
+		return err
+	}
+
+	if err := s.productRepo.DeletePriceListEntry(ctx, productID, currency); err != nil {
+		if !errors.Is(err, repository.ErrPriceListEntryNotFound) {
+			s.logger.Error("Failed to delete list price", "productID", productID, "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// normalizePrice defaults a product's currency and checks its price
+func (s *ProductService) normalizePrice(product *models.Product) error {
+	if product.Price.Currency == "" {
+		product.Price.Currency = s.currency.Default
+	}
+	currency, err := s.supportedCurrency(product.Price.Currency)
+	if err != nil {
+		return err
+	}
+	product.Price.Currency = currency
+	if product.Price.Amount < 0 {
+		return ErrInvalidPrice
+	}
+	return nil
+}
+
+// priceScale converts stored prices to currency, or the default currency
+// if it is empty, for filtering and sorting
+func (s *ProductService) priceScale(currency string) (repository.PriceScale, error) {
+	if currency == "" {
+		currency = s.currency.Default
+	}
+	currency, err := s.supportedCurrency(currency)
+	if err != nil {
+		return repository.PriceScale{}, err
+	}
+	return repository.NewPriceScale(s.currency.Rates, currency)
+}
+
+// supportedCurrency normalizes a currency code and checks it has a rate
+func (s *ProductService) supportedCurrency(currency string) (string, error) {
+	currency, err := money.NormalizeCurrency(currency)
+	if err != nil {
+		return "", err
+	}
+	if !s.currency.Rates.Supports(currency) {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	return currency, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+)
+
+var (
+	ErrForbidden         = errors.New("you do not have permission to modify this product")
+	ErrInvalidVisibility = errors.New("visibility must be one of public, unlisted or private")
+)
+
+// CanViewProduct reports whether the caller may see the product. A nil
+// claims value represents an anonymous caller.
+func CanViewProduct(claims *TokenClaims, product *models.Product) bool {
+	if product.Visibility != models.VisibilityPrivate {
+		return true
+	}
+	return CanModifyProduct(claims, product)
+}
+
+// CanModifyProduct reports whether the caller owns the product or is an admin
+func CanModifyProduct(claims *TokenClaims, product *models.Product) bool {
+	if claims == nil {
+		return false
+	}
+	return claims.Role == models.RoleAdmin || claims.UserID() == product.UserID
+}
+
+// AuthorizeRead returns ErrProductNotFound for products the caller in ctx
+// can't see, so private product IDs aren't distinguishable from missing ones
+func (s *ProductService) AuthorizeRead(ctx context.Context, product *models.Product) error {
+	claims, _ := ClaimsFromContext(ctx)
+	if !CanViewProduct(claims, product) {
+		return repository.ErrProductNotFound
+	}
+	return nil
+}
+
+// authorizeWrite hides invisible products behind ErrProductNotFound and
+// returns ErrForbidden for visible products the caller doesn't own
+func (s *ProductService) authorizeWrite(ctx context.Context, product *models.Product) error {
+	if err := s.AuthorizeRead(ctx, product); err != nil {
+		return err
+	}
+
+	claims, _ := ClaimsFromContext(ctx)
+	if !CanModifyProduct(claims, product) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// canListHidden reports whether the caller may see a user's private and
+// unlisted products in listings
+func canListHidden(ctx context.Context, ownerID uint) bool {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return false
+	}
+	return claims.Role == models.RoleAdmin || claims.UserID() == ownerID
+}
+
+// normalizeVisibility replaces an empty visibility with fallback and
+// rejects unknown values. New products fall back to public; updates keep
+// the stored visibility, so omitting it never publishes a product.
+func normalizeVisibility(product *models.Product, fallback string) error {
+	switch product.Visibility {
+	case "":
+		product.Visibility = fallback
+	case models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate:
+	default:
+		return ErrInvalidVisibility
+	}
+	return nil
+}
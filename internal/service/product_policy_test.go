@@ -0,0 +1,35 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"product-management-system/internal/models"
+)
+
+func TestNormalizeVisibility(t *testing.T) {
+	tests := []struct {
+		visibility string
+		fallback   string
+		want       string
+		err        error
+	}{
+		{"", models.VisibilityPublic, models.VisibilityPublic, nil},
+		{"", models.VisibilityPrivate, models.VisibilityPrivate, nil},
+		{models.VisibilityUnlisted, models.VisibilityPrivate, models.VisibilityUnlisted, nil},
+		{models.VisibilityPublic, models.VisibilityPrivate, models.VisibilityPublic, nil},
+		{"secret", models.VisibilityPublic, "", ErrInvalidVisibility},
+	}
+
+	for _, tt := range tests {
+		product := &models.Product{Visibility: tt.visibility}
+		err := normalizeVisibility(product, tt.fallback)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("normalizeVisibility(%q, %q) error = %v, want %v", tt.visibility, tt.fallback, err, tt.err)
+			continue
+		}
+		if err == nil && product.Visibility != tt.want {
+			t.Errorf("normalizeVisibility(%q, %q) = %q, want %q", tt.visibility, tt.fallback, product.Visibility, tt.want)
+		}
+	}
+}
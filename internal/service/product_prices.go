@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"product-management-system/pkg/money"
+	"time"
+)
+
+// scheduleBatchSize is how many due price schedules are applied per
+// transaction
+const scheduleBatchSize = 100
+
+var ErrInvalidPriceSchedule = errors.New("scheduled price must not be negative and must start in the future and end after it starts")
+
+// PriceTimeline is a product's price history and its price schedules
+type PriceTimeline struct {
+	Changes   []models.PriceChange
+	Schedules []models.PriceSchedule
+}
+
+// PriceTimeline returns the price history and schedules of a product the
+// caller can see
+func (s *ProductService) PriceTimeline(ctx context.Context, productID uint) (*PriceTimeline, error) {
+	if _, err := s.FindProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.productRepo.ListPriceChanges(ctx, productID)
+	if err != nil {
+		s.logger.Error("Failed to list price changes", "productID", productID, "error", err)
+		return nil, err
+	}
+	schedules, err := s.productRepo.ListPriceSchedules(ctx, productID)
+	if err != nil {
+		s.logger.Error("Failed to list price schedules", "productID", productID, "error", err)
+		return nil, err
+	}
+	return &PriceTimeline{Changes: changes, Schedules: schedules}, nil
+}
+
+// SchedulePrice sets a product's price from StartsAt until EndsAt, or for
+// good if EndsAt is nil
+func (s *ProductService) SchedulePrice(ctx context.Context, productID uint, schedule *models.PriceSchedule) error {
+	product, err := s.findForWrite(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if schedule.Price.Amount < 0 || !schedule.StartsAt.After(time.Now()) ||
+		(schedule.EndsAt != nil && !schedule.EndsAt.After(schedule.StartsAt)) {
+		return ErrInvalidPriceSchedule
+	}
+	if schedule.Price.Currency == "" {
+		schedule.Price.Currency = product.Price.Currency
+	}
+	if schedule.Price.Currency, err = money.NormalizeCurrency(schedule.Price.Currency); err != nil {
+		return err
+	}
+	schedule.ProductID = productID
+	schedule.BaseAmount = nil
+
+	if err := s.productRepo.CreatePriceSchedule(ctx, schedule); err != nil {
+		if !errors.Is(err, repository.ErrProductNotFound) && !errors.Is(err, repository.ErrPriceScheduleOverlap) &&
+			!errors.Is(err, repository.ErrCurrencyMismatch) {
+			s.logger.Error("Failed to schedule price", "productID", productID, "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// CancelPriceSchedule cancels a pending schedule or ends an active one
+func (s *ProductService) CancelPriceSchedule(ctx context.Context, productID, scheduleID uint) (*models.PriceSchedule, error) {
+	if _, err := s.findForWrite(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.productRepo.CancelPriceSchedule(ctx, productID, scheduleID, time.Now())
+	if err != nil {
+		if !errors.Is(err, repository.ErrPriceScheduleNotFound) && !errors.Is(err, repository.ErrPriceScheduleFinished) {
+			s.logger.Error("Failed to cancel price schedule", "scheduleID", scheduleID, "error", err)
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// RunPriceScheduler applies due price schedules every interval until ctx
+// is cancelled. onChange is called for each product whose price changed.
+func (s *ProductService) RunPriceScheduler(ctx context.Context, interval time.Duration, onChange func(productID uint)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := s.ApplyPriceSchedules(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to apply price schedules", "error", err)
+		}
+		for _, productID := range changed {
+			onChange(productID)
+		}
+		if len(changed) > 0 {
+			s.logger.Info("Applied price schedules", "products", len(changed))
+		}
+	}
+}
+
+// ApplyPriceSchedules starts and ends every due price schedule, in
+// batches, and returns the IDs of products whose price changed
+func (s *ProductService) ApplyPriceSchedules(ctx context.Context) ([]uint, error) {
+	var changed []uint
+	for {
+		handled, batch, err := s.productRepo.ApplyPriceSchedules(ctx, time.Now(), scheduleBatchSize)
+		changed = append(changed, batch...)
+		if err != nil || handled < scheduleBatchSize {
+			return changed, err
+		}
+	}
+}
@@ -2,32 +2,49 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"product-management-system/internal/models"
-	"product-management-system/internal/queue"
 	"product-management-system/internal/repository"
 	"product-management-system/pkg/logger"
+	"product-management-system/pkg/utils"
+	"slices"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrProductNameRequired = errors.New("product name is required")
+	ErrInvalidPatch        = errors.New("invalid merge patch")
+)
+
 type ProductService struct {
 	productRepo    *repository.ProductRepository
+	categoryRepo   *repository.CategoryRepository
 	imageProcessor *ImageProcessor
-	messageQueue   *queue.RabbitMQQueue
+	blobStore      BlobStore
+	uploadLimits   UploadLimits
+	currency       CurrencySettings
 	logger         *logger.Logger
 }
 
 func NewProductService(
 	productRepo *repository.ProductRepository,
+	categoryRepo *repository.CategoryRepository,
 	imageProcessor *ImageProcessor,
-	messageQueue *queue.RabbitMQQueue,
+	blobStore BlobStore,
+	uploadLimits UploadLimits,
+	currency CurrencySettings,
 	logger *logger.Logger,
 ) *ProductService {
 	return &ProductService{
 		productRepo:    productRepo,
+		categoryRepo:   categoryRepo,
 		imageProcessor: imageProcessor,
-		messageQueue:   messageQueue,
+		blobStore:      blobStore,
+		uploadLimits:   uploadLimits,
+		currency:       currency,
 		logger:         logger,
 	}
 }
@@ -35,27 +52,36 @@ func NewProductService(
 func (s *ProductService) CreateProduct(ctx context.Context, product *models.Product) error {
 	// Validate product
 	if product.ProductName == "" {
-		return errors.New("product name is required")
+		return ErrProductNameRequired
+	}
+	if err := normalizeVisibility(product, models.VisibilityPublic); err != nil {
+		return err
+	}
+	if err := s.normalizeClassification(ctx, product); err != nil {
+		return err
+	}
+	if err := s.normalizePrice(product); err != nil {
+		return err
+	}
+	resetImageStatus(product)
+
+	if product.Stock < 0 {
+		return ErrInvalidStock
 	}
 
-	// Save product
+	// The price range is derived from variants, which a new product lacks,
+	// and only reservations hold stock
+	product.PriceMinAmount = nil
+	product.PriceMaxAmount = nil
+	product.Reserved = 0
+
+	// Save product; its image processing task is written to the outbox in
+	// the same transaction
 	if err := s.productRepo.Create(ctx, product); err != nil {
 		s.logger.Error("Failed to create product", "error", err)
 		return err
 	}
 
-	// Enqueue image processing task
-	if len(product.ProductImages) > 0 {
-		task := &models.ImageProcessingTask{
-			ProductID: product.ID,
-			ImageURLs: product.ProductImages,
-		}
-		if err := s.messageQueue.EnqueueImageProcessing(task); err != nil {
-			s.logger.Error("Failed to enqueue image processing", "error", err)
-			return err
-		}
-	}
-
 	return nil
 }
 
@@ -63,19 +89,185 @@ func (s *ProductService) FindProductByID(ctx context.Context, id uint) (*models.
 	product, err := s.productRepo.FindByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
+			return nil, repository.ErrProductNotFound
+		}
+		if errors.Is(err, repository.ErrProductNotFound) {
+			return nil, err
 		}
 		s.logger.Error("Failed to find product", "error", err)
 		return nil, err
 	}
+
+	if err := s.AuthorizeRead(ctx, product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *ProductService) ListProductsByUser(ctx context.Context, filter repository.ProductFilter) (*repository.ProductPage, error) {
+	// Other callers only see the user's public catalogue
+	if !canListHidden(ctx, filter.UserID) {
+		filter.Visibility = models.VisibilityPublic
+	}
+
+	prices, err := s.priceScale(filter.Currency)
+	if err != nil {
+		return nil, err
+	}
+	filter.Prices = prices
+
+	page, err := s.productRepo.List(ctx, filter)
+	if err != nil {
+		if !errors.Is(err, repository.ErrInvalidCursor) {
+			s.logger.Error("Failed to list products by user", "error", err)
+		}
+		return nil, err
+	}
+	return page, nil
+}
+
+// UpdateProduct replaces the mutable fields of an existing product
+func (s *ProductService) UpdateProduct(ctx context.Context, id uint, input *models.Product) (*models.Product, error) {
+	existing, err := s.findForWrite(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.applyUpdate(ctx, existing, input)
+}
+
+// PatchProduct applies a JSON merge patch (RFC 7386) to an existing product
+func (s *ProductService) PatchProduct(ctx context.Context, id uint, patch []byte) (*models.Product, error) {
+	existing, err := s.findForWrite(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := json.Marshal(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal product: %w", err)
+	}
+
+	merged, err := utils.MergePatch(original, patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var input models.Product
+	if err := json.Unmarshal(merged, &input); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return s.applyUpdate(ctx, existing, &input)
+}
+
+func (s *ProductService) DeleteProduct(ctx context.Context, id uint) error {
+	if _, err := s.findForWrite(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.productRepo.Delete(ctx, id); err != nil {
+		if !errors.Is(err, repository.ErrProductNotFound) {
+			s.logger.Error("Failed to delete product", "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// findForWrite loads a product and checks the caller may modify it
+func (s *ProductService) findForWrite(ctx context.Context, id uint) (*models.Product, error) {
+	product, err := s.FindProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeWrite(ctx, product); err != nil {
+		return nil, err
+	}
 	return product, nil
 }
 
-func (s *ProductService) ListProductsByUser(ctx context.Context, userID uint, filters map[string]interface{}) ([]models.Product, error) {
-	products, err := s.productRepo.FindByUserID(ctx, userID, filters)
+// applyUpdate copies the client-editable fields of input onto existing,
+// persists the result and re-enqueues image processing if the images changed
+func (s *ProductService) applyUpdate(ctx context.Context, existing, input *models.Product) (*models.Product, error) {
+	if input.ProductName == "" {
+		return nil, ErrProductNameRequired
+	}
+	if err := normalizeVisibility(input, existing.Visibility); err != nil {
+		return nil, err
+	}
+	if err := s.normalizeClassification(ctx, input); err != nil {
+		return nil, err
+	}
+	if err := s.normalizePrice(input); err != nil {
+		return nil, err
+	}
+	if input.Stock < 0 {
+		return nil, ErrInvalidStock
+	}
+
+	imagesChanged := !slices.Equal(existing.ProductImages, input.ProductImages)
+
+	existing.ProductName = input.ProductName
+	existing.ProductDescription = input.ProductDescription
+	existing.Price = input.Price
+	existing.Stock = input.Stock
+	existing.ProductImages = input.ProductImages
+	existing.Visibility = input.Visibility
+	existing.CategoryID = input.CategoryID
+	existing.Tags = input.Tags
+	existing.Attributes = input.Attributes
+
+	// Compressed images belong to the old sources and are rebuilt by the worker
+	if imagesChanged {
+		resetImageStatus(existing)
+	}
+
+	if err := s.productRepo.Update(ctx, existing, imagesChanged); err != nil {
+		if !errors.Is(err, repository.ErrStockBelowReserved) && !errors.Is(err, repository.ErrVariantPricesSet) {
+			s.logger.Error("Failed to update product", "error", err)
+		}
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// resetImageStatus discards processing results and marks a product's
+// images as awaiting processing
+func resetImageStatus(product *models.Product) {
+	product.ProcessedImages = nil
+	product.ImageStatus = ""
+	product.ImageError = ""
+	product.ProcessedAt = nil
+	if len(product.ProductImages) > 0 {
+		product.ImageStatus = models.ImageStatusPending
+	}
+}
+
+// SearchProducts runs a full-text search over the products the caller can
+// see: public products, plus their own, plus everything for admins
+func (s *ProductService) SearchProducts(ctx context.Context, filter repository.SearchFilter) (*repository.SearchResult, error) {
+	filter.ViewerID = 0
+	filter.IncludeHidden = false
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		filter.ViewerID = claims.UserID()
+		filter.IncludeHidden = claims.Role == models.RoleAdmin
+	}
+
+	prices, err := s.priceScale("")
+	if err != nil {
+		return nil, err
+	}
+	filter.Prices = prices
+
+	result, err := s.productRepo.Search(ctx, filter)
 	if err != nil {
-		s.logger.Error("Failed to list products by user", "error", err)
+		if !errors.Is(err, repository.ErrEmptySearchQuery) {
+			s.logger.Error("Failed to search products", "error", err)
+		}
 		return nil, err
 	}
-	return products, nil
+	return result, nil
 }
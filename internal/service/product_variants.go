@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"product-management-system/internal/models"
+	"product-management-system/internal/repository"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxVariantOptions = 10
+	maxOptionLength   = 64
+	maxVariantImages  = 10
+)
+
+var (
+	ErrInvalidSKU            = errors.New("SKU must be 1-64 letters, digits, dots, underscores or hyphens")
+	ErrInvalidVariantOptions = errors.New("variant options must be at most 10 non-empty names and values of up to 64 characters")
+	ErrInvalidVariantPrice   = errors.New("variant price must not be negative")
+	ErrInvalidVariantImages  = errors.New("a variant can have at most 10 non-empty image URLs")
+	ErrInvalidStock          = errors.New("stock must not be negative")
+)
+
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ListVariants returns the variants of a product the caller can see
+func (s *ProductService) ListVariants(ctx context.Context, productID uint) ([]models.ProductVariant, error) {
+	if _, err := s.FindProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	variants, err := s.productRepo.ListVariants(ctx, productID)
+	if err != nil {
+		s.logger.Error("Failed to list variants", "productID", productID, "error", err)
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (s *ProductService) FindVariant(ctx context.Context, productID, variantID uint) (*models.ProductVariant, error) {
+	if _, err := s.FindProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	variant, err := s.productRepo.FindVariant(ctx, productID, variantID)
+	if err != nil && !errors.Is(err, repository.ErrVariantNotFound) {
+		s.logger.Error("Failed to find variant", "productID", productID, "error", err)
+	}
+	return variant, err
+}
+
+func (s *ProductService) CreateVariant(ctx context.Context, productID uint, variant *models.ProductVariant) error {
+	if _, err := s.findForWrite(ctx, productID); err != nil {
+		return err
+	}
+	if err := normalizeVariant(variant); err != nil {
+		return err
+	}
+	variant.ProductID = productID
+	variant.Reserved = 0
+
+	if err := s.productRepo.CreateVariant(ctx, variant); err != nil {
+		if !isVariantClientError(err) {
+			s.logger.Error("Failed to create variant", "productID", productID, "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateVariant replaces the editable fields of a variant
+func (s *ProductService) UpdateVariant(ctx context.Context, productID, variantID uint, input *models.ProductVariant) (*models.ProductVariant, error) {
+	if _, err := s.findForWrite(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	variant, err := s.productRepo.FindVariant(ctx, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	variant.SKU = input.SKU
+	variant.Options = input.Options
+	variant.PriceAmount = input.PriceAmount
+	variant.Images = input.Images
+	variant.Stock = input.Stock
+	if err := normalizeVariant(variant); err != nil {
+		return nil, err
+	}
+
+	if err := s.productRepo.UpdateVariant(ctx, variant); err != nil {
+		if !isVariantClientError(err) {
+			s.logger.Error("Failed to update variant", "productID", productID, "error", err)
+		}
+		return nil, err
+	}
+	return variant, nil
+}
+
+func (s *ProductService) DeleteVariant(ctx context.Context, productID, variantID uint) error {
+	if _, err := s.findForWrite(ctx, productID); err != nil {
+		return err
+	}
+
+	if err := s.productRepo.DeleteVariant(ctx, productID, variantID); err != nil {
+		if !isVariantClientError(err) {
+			s.logger.Error("Failed to delete variant", "productID", productID, "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// normalizeVariant trims a variant's SKU and options and validates its
+// fields
+func normalizeVariant(variant *models.ProductVariant) error {
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	if !skuPattern.MatchString(variant.SKU) {
+		return ErrInvalidSKU
+	}
+
+	if len(variant.Options) > maxVariantOptions {
+		return ErrInvalidVariantOptions
+	}
+	options := make(models.VariantOptions, len(variant.Options))
+	for name, value := range variant.Options {
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || value == "" || utf8.RuneCountInString(name) > maxOptionLength || utf8.RuneCountInString(value) > maxOptionLength {
+			return ErrInvalidVariantOptions
+		}
+		if _, ok := options[name]; ok {
+			return ErrInvalidVariantOptions
+		}
+		options[name] = value
+	}
+	variant.Options = options
+
+	if variant.PriceAmount != nil && *variant.PriceAmount < 0 {
+		return ErrInvalidVariantPrice
+	}
+	if variant.Stock < 0 {
+		return ErrInvalidStock
+	}
+
+	if len(variant.Images) > maxVariantImages {
+		return ErrInvalidVariantImages
+	}
+	for _, image := range variant.Images {
+		if strings.TrimSpace(image) == "" {
+			return ErrInvalidVariantImages
+		}
+	}
+	return nil
+}
+
+func isVariantClientError(err error) bool {
+	return errors.Is(err, repository.ErrProductNotFound) ||
+		errors.Is(err, repository.ErrVariantNotFound) ||
+		errors.Is(err, repository.ErrSKUExists) ||
+		errors.Is(err, repository.ErrVariantOptionsExist) ||
+		errors.Is(err, repository.ErrStockBelowReserved)
+}
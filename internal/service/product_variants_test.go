@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"product-management-system/internal/models"
+)
+
+func TestNormalizeVariant(t *testing.T) {
+	negative, zero := int64(-1), int64(0)
+	tooManyOptions := models.VariantOptions{}
+	for i := 0; i <= maxVariantOptions; i++ {
+		tooManyOptions[fmt.Sprintf("option%d", i)] = "x"
+	}
+
+	tests := []struct {
+		name    string
+		variant models.ProductVariant
+		err     error
+	}{
+		{"minimal", models.ProductVariant{SKU: "A"}, nil},
+		{"all fields", models.ProductVariant{SKU: "TEE-RED_M.2", Options: models.VariantOptions{"size": "M"}, PriceAmount: &zero, Images: []string{"https://example.com/a.jpg"}, Stock: 3}, nil},
+		{"longest SKU", models.ProductVariant{SKU: strings.Repeat("A", 64)}, nil},
+		{"empty SKU", models.ProductVariant{SKU: "  "}, ErrInvalidSKU},
+		{"SKU too long", models.ProductVariant{SKU: strings.Repeat("A", 65)}, ErrInvalidSKU},
+		{"SKU with space", models.ProductVariant{SKU: "TEE RED"}, ErrInvalidSKU},
+		{"SKU with slash", models.ProductVariant{SKU: "TEE/RED"}, ErrInvalidSKU},
+		{"non-ASCII SKU", models.ProductVariant{SKU: "TÉE"}, ErrInvalidSKU},
+		{"empty option name", models.ProductVariant{SKU: "A", Options: models.VariantOptions{" ": "M"}}, ErrInvalidVariantOptions},
+		{"empty option value", models.ProductVariant{SKU: "A", Options: models.VariantOptions{"size": " "}}, ErrInvalidVariantOptions},
+		{"option names equal once trimmed", models.ProductVariant{SKU: "A", Options: models.VariantOptions{"size": "M", " size ": "L"}}, ErrInvalidVariantOptions},
+		{"option value too long", models.ProductVariant{SKU: "A", Options: models.VariantOptions{"size": strings.Repeat("x", maxOptionLength+1)}}, ErrInvalidVariantOptions},
+		{"multibyte option value counts characters", models.ProductVariant{SKU: "A", Options: models.VariantOptions{"farbe": strings.Repeat("ü", maxOptionLength)}}, nil},
+		{"too many options", models.ProductVariant{SKU: "A", Options: tooManyOptions}, ErrInvalidVariantOptions},
+		{"negative price", models.ProductVariant{SKU: "A", PriceAmount: &negative}, ErrInvalidVariantPrice},
+		{"negative stock", models.ProductVariant{SKU: "A", Stock: -1}, ErrInvalidStock},
+		{"empty image", models.ProductVariant{SKU: "A", Images: []string{" "}}, ErrInvalidVariantImages},
+		{"too many images", models.ProductVariant{SKU: "A", Images: strings.Fields(strings.Repeat("x ", maxVariantImages+1))}, ErrInvalidVariantImages},
+	}
+	for _, tt := range tests {
+		variant := tt.variant
+		if err := normalizeVariant(&variant); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestNormalizeVariantTrims(t *testing.T) {
+	variant := models.ProductVariant{SKU: " TEE-M ", Options: models.VariantOptions{" size ": " M "}}
+	if err := normalizeVariant(&variant); err != nil {
+		t.Fatal(err)
+	}
+	if variant.SKU != "TEE-M" || len(variant.Options) != 1 || variant.Options["size"] != "M" {
+		t.Errorf("normalized to SKU %q, options %v", variant.SKU, variant.Options)
+	}
+
+	// Variants without options are stored with an empty set, so they
+	// conflict with each other
+	variant = models.ProductVariant{SKU: "A"}
+	if err := normalizeVariant(&variant); err != nil {
+		t.Fatal(err)
+	}
+	if variant.Options == nil {
+		t.Error("Options is nil, want an empty map")
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+
+	"product-management-system/internal/cache"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the PostgreSQL database in TEST_DATABASE_DSN,
+// migrates models and empties their tables. Tests that need a database
+// are skipped when the variable isn't set.
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec("TRUNCATE " + stmt.Schema.Table + " RESTART IDENTITY CASCADE").Error; err != nil {
+			t.Fatalf("failed to empty %s: %v", stmt.Schema.Table, err)
+		}
+	}
+	return db
+}
+
+// openTestRedis connects to the Redis server at TEST_REDIS_ADDR
+// (host:port), skipping the test when it isn't set
+func openTestRedis(t *testing.T) *cache.RedisCache {
+	t.Helper()
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+	host, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("invalid TEST_REDIS_ADDR: %v", err)
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		t.Fatalf("invalid TEST_REDIS_ADDR port: %v", err)
+	}
+
+	redisCache := cache.NewRedisCache(host, port, "", "")
+	t.Cleanup(func() { redisCache.Close() })
+	return redisCache
+}
@@ -0,0 +1,99 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown ISO 4217 currency code")
+	ErrInvalidAmount   = errors.New("invalid amount for currency")
+)
+
+// exponents holds the number of minor unit digits of ISO 4217 currencies.
+// Currencies not listed here are rejected.
+var exponents = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BGN": 2, "BHD": 3, "BRL": 2, "CAD": 2,
+	"CHF": 2, "CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2,
+	"IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KES": 2, "KRW": 0, "KWD": 3,
+	"LYD": 3, "MAD": 2, "MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NZD": 2,
+	"OMR": 3, "PEN": 2, "PHP": 2, "PKR": 2, "PLN": 2, "PYG": 0, "QAR": 2,
+	"RON": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"TWD": 2, "UAH": 2, "UGX": 0, "USD": 2, "VND": 0, "XAF": 0, "XOF": 0,
+	"ZAR": 2,
+}
+
+// Money is an exact amount in the minor units of a currency, e.g. 1999
+// USD is $19.99 and 1999 JPY is ¥1999. The JSON name amount_minor keeps
+// it apart from the major-unit amounts used in query parameters.
+type Money struct {
+	Amount   int64  `json:"amount_minor" gorm:"not null;default:0"`
+	Currency string `json:"currency" gorm:"size:3;not null;default:''"`
+}
+
+// New returns Money after checking the currency code
+func New(amount int64, currency string) (Money, error) {
+	if _, err := Exponent(currency); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Exponent returns the number of minor unit digits of a currency
+func Exponent(currency string) (int, error) {
+	exponent, ok := exponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return exponent, nil
+}
+
+// NormalizeCurrency upper-cases a currency code and checks it is known
+func NormalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if _, err := Exponent(currency); err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+// Parse reads a decimal amount such as "19.99" in major units. Amounts
+// with more fractional digits than the currency has are rejected rather
+// than rounded.
+func Parse(amount, currency string) (Money, error) {
+	exponent, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok || strings.ContainsAny(amount, "eE/") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	value.Mul(value, pow10(exponent))
+	if !value.IsInt() || !value.Num().IsInt64() {
+		return Money{}, fmt.Errorf("%w: %q has too many decimals for %s", ErrInvalidAmount, amount, currency)
+	}
+	return Money{Amount: value.Num().Int64(), Currency: currency}, nil
+}
+
+// Decimal formats the amount in major units, e.g. "19.99"
+func (m Money) Decimal() string {
+	exponent, err := Exponent(m.Currency)
+	if err != nil {
+		exponent = 0
+	}
+	return new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(exponent).Num()).FloatString(exponent)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// pow10 returns 10^n as a rational
+func pow10(n int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
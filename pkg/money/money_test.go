@@ -0,0 +1,83 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     Money
+		err      error
+	}{
+		{"19.99", "USD", Money{1999, "USD"}, nil},
+		{"19.9", "USD", Money{1990, "USD"}, nil},
+		{"19", "USD", Money{1900, "USD"}, nil},
+		{"0.01", "EUR", Money{1, "EUR"}, nil},
+		{"-5.25", "USD", Money{-525, "USD"}, nil},
+		{"1999", "JPY", Money{1999, "JPY"}, nil},
+		{"1.234", "KWD", Money{1234, "KWD"}, nil},
+		{"19.999", "USD", Money{}, ErrInvalidAmount},
+		{"1.5", "JPY", Money{}, ErrInvalidAmount},
+		{"1e3", "USD", Money{}, ErrInvalidAmount},
+		{"1/2", "USD", Money{}, ErrInvalidAmount},
+		{"abc", "USD", Money{}, ErrInvalidAmount},
+		{"", "USD", Money{}, ErrInvalidAmount},
+		{"100000000000000000", "USD", Money{}, ErrInvalidAmount},
+		{"1.00", "XXX", Money{}, ErrUnknownCurrency},
+		{"1.00", "usd", Money{}, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.amount, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q, %q) error = %v, want %v", tt.amount, tt.currency, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %q) = %+v, want %+v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{1999, "USD"}, "19.99"},
+		{Money{5, "USD"}, "0.05"},
+		{Money{-525, "USD"}, "-5.25"},
+		{Money{1999, "JPY"}, "1999"},
+		{Money{1234, "KWD"}, "1.234"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("%+v.Decimal() = %q, want %q", tt.money, got, tt.want)
+		}
+		parsed, err := Parse(tt.want, tt.money.Currency)
+		if err != nil || parsed != tt.money {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", tt.want, parsed, err, tt.money)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(Money{Amount: 1999, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount_minor":1999,"currency":"USD"}`; string(data) != want {
+		t.Fatalf("json = %s, want %s", data, want)
+	}
+
+	var decoded Money
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != (Money{Amount: 1999, Currency: "USD"}) {
+		t.Fatalf("decoded = %+v", decoded)
+	}
+}
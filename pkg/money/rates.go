@@ -0,0 +1,135 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+)
+
+var ErrNoRate = errors.New("no conversion rate for currency")
+
+// Rates converts between currencies using rates relative to a base
+// currency. Rates are kept as exact rationals.
+type Rates struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+// ratesFile is the format read by LoadRates. Rates are units of each
+// currency per unit of the base, written as strings or numbers, e.g.
+// {"base": "USD", "rates": {"EUR": "0.92", "JPY": "151.3"}}
+type ratesFile struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// NewRates builds a rate table. The base currency always has rate 1.
+func NewRates(base string, rates map[string]string) (*Rates, error) {
+	base, err := NormalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rates{base: base, rates: map[string]*big.Rat{base: big.NewRat(1, 1)}}
+	for code, text := range rates {
+		currency, err := NormalizeCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(text)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", text, currency)
+		}
+		if currency == base && rate.Cmp(big.NewRat(1, 1)) != 0 {
+			return nil, fmt.Errorf("base currency %s must have rate 1", base)
+		}
+		r.rates[currency] = rate
+	}
+	return r, nil
+}
+
+// LoadRates reads a JSON rate table from path
+func LoadRates(path string) (*Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	rates := make(map[string]string, len(file.Rates))
+	for code, rate := range file.Rates {
+		rates[code] = rate.String()
+	}
+	return NewRates(file.Base, rates)
+}
+
+func (r *Rates) Base() string {
+	return r.base
+}
+
+// Supports reports whether the table has a rate for currency
+func (r *Rates) Supports(currency string) bool {
+	_, ok := r.rates[currency]
+	return ok
+}
+
+// Currencies lists the currencies with a rate, sorted
+func (r *Rates) Currencies() []string {
+	currencies := make([]string, 0, len(r.rates))
+	for currency := range r.rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// Factor returns the exact factor that turns minor units of from into
+// major units of to
+func (r *Rates) Factor(from, to string) (*big.Rat, error) {
+	fromRate, toRate := r.rates[from], r.rates[to]
+	if fromRate == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoRate, from)
+	}
+	if toRate == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoRate, to)
+	}
+	exponent, err := Exponent(from)
+	if err != nil {
+		return nil, err
+	}
+
+	factor := new(big.Rat).Quo(toRate, fromRate)
+	return factor.Quo(factor, pow10(exponent)), nil
+}
+
+// Convert converts m to currency to, rounding to whole minor units with
+// mode. Amounts already in the target currency are returned unchanged.
+func (r *Rates) Convert(m Money, to string, mode Rounding) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+
+	factor, err := r.Factor(m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+	exponent, err := Exponent(to)
+	if err != nil {
+		return Money{}, err
+	}
+
+	value := new(big.Rat).SetInt64(m.Amount)
+	value.Mul(value, factor)
+	value.Mul(value, pow10(exponent))
+	amount := mode.Round(value)
+	if !amount.IsInt64() {
+		return Money{}, fmt.Errorf("%w: converted amount overflows", ErrInvalidAmount)
+	}
+	return Money{Amount: amount.Int64(), Currency: to}, nil
+}
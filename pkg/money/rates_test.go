@@ -0,0 +1,134 @@
+package money
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	rates, err := NewRates("USD", map[string]string{"EUR": "0.92", "JPY": "151.3", "KWD": "0.307"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from Money
+		to   string
+		mode Rounding
+		want Money
+		err  error
+	}{
+		{Money{1999, "USD"}, "USD", RoundHalfEven, Money{1999, "USD"}, nil},
+		{Money{1000, "USD"}, "EUR", RoundHalfEven, Money{920, "EUR"}, nil},
+		// 1999 * 0.92 = 1839.08
+		{Money{1999, "USD"}, "EUR", RoundHalfEven, Money{1839, "EUR"}, nil},
+		{Money{1999, "USD"}, "EUR", RoundUp, Money{1840, "EUR"}, nil},
+		// $19.99 * 151.3 = ¥3024.487
+		{Money{1999, "USD"}, "JPY", RoundHalfEven, Money{3024, "JPY"}, nil},
+		{Money{1999, "USD"}, "JPY", RoundUp, Money{3025, "JPY"}, nil},
+		// ¥1000 / 151.3 = $6.6093...
+		{Money{1000, "JPY"}, "USD", RoundHalfEven, Money{661, "USD"}, nil},
+		{Money{1000, "JPY"}, "USD", RoundDown, Money{660, "USD"}, nil},
+		// €0.46 / 0.92 = $0.50 exactly
+		{Money{46, "EUR"}, "USD", RoundDown, Money{50, "USD"}, nil},
+		// $0.05 * 0.92 = €0.046
+		{Money{5, "USD"}, "EUR", RoundHalfUp, Money{5, "EUR"}, nil},
+		// $0.50 * 0.307 = 0.1535 KWD
+		{Money{50, "USD"}, "KWD", RoundHalfEven, Money{154, "KWD"}, nil},
+		{Money{50, "USD"}, "KWD", RoundDown, Money{153, "KWD"}, nil},
+		{Money{-1999, "USD"}, "EUR", RoundUp, Money{-1840, "EUR"}, nil},
+		{Money{1999, "USD"}, "GBP", RoundHalfEven, Money{}, ErrNoRate},
+		{Money{1999, "GBP"}, "USD", RoundHalfEven, Money{}, ErrNoRate},
+		{Money{1 << 62, "USD"}, "KWD", RoundHalfEven, Money{}, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := rates.Convert(tt.from, tt.to, tt.mode)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Convert(%+v, %s, %s) error = %v, want %v", tt.from, tt.to, tt.mode, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Convert(%+v, %s, %s) = %+v, want %+v", tt.from, tt.to, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestConvertHalfEvenTies(t *testing.T) {
+	// A rate of 0.5 puts odd amounts exactly on a tie
+	rates, err := NewRates("USD", map[string]string{"EUR": "0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		amount int64
+		mode   Rounding
+		want   int64
+	}{
+		{5, RoundHalfEven, 2},
+		{7, RoundHalfEven, 4},
+		{5, RoundHalfUp, 3},
+		{7, RoundHalfUp, 4},
+		{5, RoundDown, 2},
+		{5, RoundUp, 3},
+	}
+	for _, tt := range tests {
+		got, err := rates.Convert(Money{tt.amount, "USD"}, "EUR", tt.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Amount != tt.want {
+			t.Errorf("Convert(%d USD, %s) = %d, want %d", tt.amount, tt.mode, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestNewRates(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		rates map[string]string
+		ok    bool
+	}{
+		{"lower-case codes", "usd", map[string]string{"eur": "0.92"}, true},
+		{"base listed at 1", "USD", map[string]string{"USD": "1.0"}, true},
+		{"unknown base", "XXX", nil, false},
+		{"unknown currency", "USD", map[string]string{"XXX": "1"}, false},
+		{"zero rate", "USD", map[string]string{"EUR": "0"}, false},
+		{"negative rate", "USD", map[string]string{"EUR": "-1"}, false},
+		{"not a number", "USD", map[string]string{"EUR": "abc"}, false},
+		{"base not 1", "USD", map[string]string{"USD": "2"}, false},
+	}
+	for _, tt := range tests {
+		rates, err := NewRates(tt.base, tt.rates)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		if tt.ok && (rates.Base() != "USD" || !rates.Supports("USD")) {
+			t.Errorf("%s: base = %q", tt.name, rates.Base())
+		}
+	}
+}
+
+func TestLoadRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	data := `{"base": "USD", "rates": {"EUR": "0.92", "JPY": 151.3}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := LoadRates(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rates.Currencies(); len(got) != 3 || got[0] != "EUR" || got[1] != "JPY" || got[2] != "USD" {
+		t.Fatalf("Currencies() = %v", got)
+	}
+	converted, err := rates.Convert(Money{1000, "USD"}, "JPY", RoundHalfEven)
+	if err != nil || converted.Amount != 1513 {
+		t.Fatalf("Convert = %+v, %v", converted, err)
+	}
+}
@@ -0,0 +1,66 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Rounding says how a converted amount that falls between two minor units
+// is rounded
+type Rounding string
+
+const (
+	// RoundHalfEven rounds ties to the even neighbour (banker's rounding)
+	RoundHalfEven Rounding = "half_even"
+	// RoundHalfUp rounds ties away from zero
+	RoundHalfUp Rounding = "half_up"
+	// RoundDown truncates toward zero
+	RoundDown Rounding = "down"
+	// RoundUp rounds away from zero
+	RoundUp Rounding = "up"
+)
+
+// ParseRounding validates a rounding mode name
+func ParseRounding(name string) (Rounding, error) {
+	switch mode := Rounding(name); mode {
+	case RoundHalfEven, RoundHalfUp, RoundDown, RoundUp:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rounding mode %q", name)
+	}
+}
+
+// Round rounds value to an integer using mode
+func (mode Rounding) Round(value *big.Rat) *big.Int {
+	num, denom := value.Num(), value.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, denom, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// away moves the quotient one unit away from zero
+	away := func() *big.Int {
+		return quotient.Add(quotient, big.NewInt(int64(value.Sign())))
+	}
+
+	switch mode {
+	case RoundDown:
+		return quotient
+	case RoundUp:
+		return away()
+	}
+
+	// Compare twice the remainder with the denominator to find ties
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	switch twice.Cmp(denom) {
+	case 1:
+		return away()
+	case -1:
+		return quotient
+	}
+	if mode == RoundHalfEven && quotient.Bit(0) == 0 {
+		return quotient
+	}
+	return away()
+}
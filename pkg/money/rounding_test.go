@@ -0,0 +1,48 @@
+package money
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[Rounding]int64
+	}{
+		{"2.5", map[Rounding]int64{RoundHalfEven: 2, RoundHalfUp: 3, RoundDown: 2, RoundUp: 3}},
+		{"3.5", map[Rounding]int64{RoundHalfEven: 4, RoundHalfUp: 4, RoundDown: 3, RoundUp: 4}},
+		{"-2.5", map[Rounding]int64{RoundHalfEven: -2, RoundHalfUp: -3, RoundDown: -2, RoundUp: -3}},
+		{"-3.5", map[Rounding]int64{RoundHalfEven: -4, RoundHalfUp: -4, RoundDown: -3, RoundUp: -4}},
+		{"2.4", map[Rounding]int64{RoundHalfEven: 2, RoundHalfUp: 2, RoundDown: 2, RoundUp: 3}},
+		{"2.6", map[Rounding]int64{RoundHalfEven: 3, RoundHalfUp: 3, RoundDown: 2, RoundUp: 3}},
+		{"-2.6", map[Rounding]int64{RoundHalfEven: -3, RoundHalfUp: -3, RoundDown: -2, RoundUp: -3}},
+		{"0.1", map[Rounding]int64{RoundHalfEven: 0, RoundHalfUp: 0, RoundDown: 0, RoundUp: 1}},
+		{"7", map[Rounding]int64{RoundHalfEven: 7, RoundHalfUp: 7, RoundDown: 7, RoundUp: 7}},
+		{"1/3", map[Rounding]int64{RoundHalfEven: 0, RoundHalfUp: 0, RoundDown: 0, RoundUp: 1}},
+	}
+	for _, tt := range tests {
+		value, ok := new(big.Rat).SetString(tt.value)
+		if !ok {
+			t.Fatalf("bad test value %q", tt.value)
+		}
+		for mode, want := range tt.want {
+			if got := mode.Round(value); got.Int64() != want {
+				t.Errorf("%s.Round(%s) = %s, want %d", mode, tt.value, got, want)
+			}
+		}
+	}
+}
+
+func TestParseRounding(t *testing.T) {
+	for _, name := range []string{"half_even", "half_up", "down", "up"} {
+		if mode, err := ParseRounding(name); err != nil || string(mode) != name {
+			t.Errorf("ParseRounding(%q) = %q, %v", name, mode, err)
+		}
+	}
+	for _, name := range []string{"", "HALF_EVEN", "nearest"} {
+		if _, err := ParseRounding(name); err == nil {
+			t.Errorf("ParseRounding(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrBlockedAddress    = errors.New("fetch: destination address is not allowed")
+	ErrUnsupportedScheme = errors.New("fetch: only http and https URLs are allowed")
+	ErrTooManyRedirects  = errors.New("fetch: too many redirects")
+	ErrResponseTooLarge  = errors.New("fetch: response exceeds the size limit")
+	ErrImageTooLarge     = errors.New("image dimensions exceed the pixel limit")
+)
+
+// blockedPrefixes are ranges outside the netip helpers that must not be
+// reachable from a fetch: "this network", carrier-grade NAT, IETF protocol
+// assignments, benchmarking, the reserved class E range, and the NAT64 and
+// 6to4 prefixes, which embed an arbitrary IPv4 address
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// FetcherConfig limits what a Fetcher downloads and from where
+type FetcherConfig struct {
+	// ConnectTimeout bounds dialing and the TLS handshake
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the whole request, including reading the body
+	ReadTimeout  time.Duration
+	MaxBytes     int64
+	MaxRedirects int
+	// MaxPixels rejects images whose declared width x height exceeds it
+	// before they are decoded
+	MaxPixels int64
+	// AllowedHosts are hostnames or CIDR ranges that may be fetched even
+	// though they resolve to private or loopback addresses
+	AllowedHosts []string
+}
+
+// DefaultFetcherConfig returns conservative limits for fetching images
+func DefaultFetcherConfig() FetcherConfig {
+	return FetcherConfig{
+		ConnectTimeout: 5 * time.Second,
+		ReadTimeout:    30 * time.Second,
+		MaxBytes:       25 << 20,
+		MaxRedirects:   3,
+		MaxPixels:      50_000_000,
+	}
+}
+
+// Fetcher downloads remote resources without letting a URL reach internal
+// services. Destination addresses are checked when connecting, after DNS
+// resolution, so redirects and DNS rebinding can't get around the check.
+type Fetcher struct {
+	client       *http.Client
+	config       FetcherConfig
+	allowedHosts map[string]bool
+	allowedNets  []netip.Prefix
+}
+
+func NewFetcher(config FetcherConfig) (*Fetcher, error) {
+	f := &Fetcher{config: config, allowedHosts: make(map[string]bool)}
+	for _, entry := range config.AllowedHosts {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			f.allowedNets = append(f.allowedNets, prefix)
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			f.allowedNets = append(f.allowedNets, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		if entry == "" || strings.ContainsAny(entry, "/:") {
+			return nil, fmt.Errorf("invalid allowed host %q", entry)
+		}
+		f.allowedHosts[strings.ToLower(entry)] = true
+	}
+
+	dialer := &net.Dialer{Timeout: config.ConnectTimeout}
+	guardedDialer := &net.Dialer{Timeout: config.ConnectTimeout, Control: f.checkAddress}
+
+	transport := &http.Transport{
+		// Never route through an environment proxy, which would hide the
+		// real destination from the address check
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			if f.allowedHosts[strings.ToLower(host)] {
+				return dialer.DialContext(ctx, network, addr)
+			}
+			return guardedDialer.DialContext(ctx, network, addr)
+		},
+		TLSHandshakeTimeout:   config.ConnectTimeout,
+		ResponseHeaderTimeout: config.ReadTimeout,
+		MaxIdleConnsPerHost:   4,
+	}
+
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   config.ReadTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return checkScheme(req.URL.Scheme)
+		},
+	}
+	return f, nil
+}
+
+// Fetch downloads url and returns its body
+func (f *Fetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(req.URL.Scheme); err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("fetch %s: unexpected status %s", url, resp.Status)
+	}
+	if resp.ContentLength > f.config.MaxBytes {
+		return nil, ErrResponseTooLarge
+	}
+
+	return ReadLimited(resp.Body, f.config.MaxBytes)
+}
+
+// FetchImage downloads an image and checks its declared dimensions
+func (f *Fetcher) FetchImage(ctx context.Context, url string) ([]byte, error) {
+	data, err := f.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.CheckImage(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// CheckImage guards against decompression bombs by reading the image
+// header and rejecting images with more than MaxPixels pixels. Images
+// whose header can't be read are rejected too, since their size is unknown.
+func (f *Fetcher) CheckImage(data []byte) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to read image header: %w", err)
+	}
+	if int64(config.Width)*int64(config.Height) > f.config.MaxPixels {
+		return fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+	return nil
+}
+
+// ReadLimited reads r to the end, failing if it is longer than maxBytes
+func ReadLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrResponseTooLarge
+	}
+	return data, nil
+}
+
+// checkAddress runs before every guarded connection with the resolved
+// address
+func (f *Fetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	addr := addrPort.Addr().Unmap()
+
+	for _, prefix := range f.allowedNets {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	if isInternalAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+	return nil
+}
+
+// isInternalAddress reports addresses that aren't on the public internet,
+// including the cloud metadata endpoint 169.254.169.254
+func isInternalAddress(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkScheme(scheme string) error {
+	if scheme != "http" && scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	return nil
+}
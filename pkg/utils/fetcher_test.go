@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"net/netip"
+	"testing"
+)
+
+func TestIsInternalAddress(t *testing.T) {
+	tests := []struct {
+		addr     string
+		internal bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"192.0.0.170", true},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"240.0.0.1", true},
+		{"255.255.255.255", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fc00::1", true},
+		{"fe80::1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"2002:a9fe:a9fe::1", true},
+		{"93.184.216.34", false},
+		{"192.0.2.1", false},
+		{"198.20.0.1", false},
+		{"2606:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		if got := isInternalAddress(netip.MustParseAddr(tt.addr)); got != tt.internal {
+			t.Errorf("isInternalAddress(%s) = %v, want %v", tt.addr, got, tt.internal)
+		}
+	}
+}
+
+func TestCheckAddressAllowsConfiguredNets(t *testing.T) {
+	config := DefaultFetcherConfig()
+	config.AllowedHosts = []string{"10.0.0.0/8"}
+	f, err := NewFetcher(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.checkAddress("tcp4", "10.1.2.3:80", nil); err != nil {
+		t.Errorf("allowed net rejected: %v", err)
+	}
+	if err := f.checkAddress("tcp4", "192.168.1.1:80", nil); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("private address: %v, want %v", err, ErrBlockedAddress)
+	}
+	if err := f.checkAddress("tcp6", "[64:ff9b::a00:1]:80", nil); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("NAT64 address: %v, want %v", err, ErrBlockedAddress)
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	config := DefaultFetcherConfig()
+	config.MaxPixels = 100
+	f, err := NewFetcher(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(width, height int) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	if err := f.CheckImage(encode(10, 10)); err != nil {
+		t.Errorf("image at the limit rejected: %v", err)
+	}
+	if err := f.CheckImage(encode(11, 10)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("oversized image: %v, want %v", err, ErrImageTooLarge)
+	}
+	if err := f.CheckImage([]byte("not an image")); err == nil {
+		t.Error("unreadable header accepted")
+	}
+}
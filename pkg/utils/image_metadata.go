@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ImageMetadata holds the EXIF fields the image pipeline uses. Everything
+// else, including GPS coordinates, is dropped when images are re-encoded.
+type ImageMetadata struct {
+	// Orientation is the EXIF orientation (1-8), 0 when absent
+	Orientation int
+	CapturedAt  *time.Time
+	CameraModel string
+}
+
+// ReadImageMetadata extracts EXIF metadata from a JPEG or WebP image.
+// Images without EXIF, or with EXIF that can't be parsed, yield the zero
+// value.
+func ReadImageMetadata(data []byte) (meta ImageMetadata) {
+	// goexif can panic on corrupt input, which must not take down the worker
+	defer func() {
+		if recover() != nil {
+			meta = ImageMetadata{}
+		}
+	}()
+
+	if payload, ok := webpExifChunk(data); ok {
+		data = payload
+	}
+
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil && (x == nil || exif.IsCriticalError(err)) {
+		return meta
+	}
+
+	if tag, err := x.Get(exif.Orientation); err == nil {
+		if orientation, err := tag.Int(0); err == nil && orientation >= 1 && orientation <= 8 {
+			meta.Orientation = orientation
+		}
+	}
+	if capturedAt, err := x.DateTime(); err == nil {
+		meta.CapturedAt = &capturedAt
+	}
+	if tag, err := x.Get(exif.Model); err == nil {
+		if model, err := tag.StringVal(); err == nil {
+			meta.CameraModel = strings.TrimSpace(strings.TrimRight(model, "\x00"))
+		}
+	}
+	return meta
+}
+
+// webpExifChunk returns the payload of a WebP file's EXIF chunk
+func webpExifChunk(data []byte) ([]byte, bool) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false
+	}
+
+	for pos := 12; pos+8 <= len(data); {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		start := pos + 8
+		if size < 0 || start+size > len(data) {
+			return nil, false
+		}
+		if fourCC == "EXIF" {
+			return data[start : start+size], true
+		}
+		pos = start + size + size&1
+	}
+	return nil, false
+}
+
+// Orient rotates and flips img so it displays upright according to an EXIF
+// orientation value. Orientations outside 2-8 return img unchanged.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+
+	// Orientations 5-8 swap width and height
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs 90° clockwise rotation
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs 90° counter-clockwise rotation
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// StripMetadata removes EXIF, XMP, IPTC and text metadata from a JPEG,
+// PNG or WebP image without re-encoding it. The orientation, capture time
+// and camera model the pipeline reads are kept in a minimal EXIF block, so
+// GPS coordinates and everything else are gone. GIFs carry no such
+// metadata and are returned unchanged.
+func StripMetadata(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatJPEG:
+		return stripJPEG(data, ReadImageMetadata(data))
+	case FormatPNG:
+		return stripPNG(data)
+	case FormatWebP:
+		return stripWebP(data, ReadImageMetadata(data))
+	case FormatGIF:
+		return data, nil
+	default:
+		return nil, fmt.Errorf("can't strip metadata from %q images", format)
+	}
+}
+
+var errMalformedImage = errors.New("malformed image")
+
+// stripJPEG drops APP1 (EXIF, XMP), APP12, APP13 (IPTC) and comment
+// segments, inserting meta as a new EXIF segment after any JFIF header
+func stripJPEG(data []byte, meta ImageMetadata) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformedImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	exifWritten := meta == ImageMetadata{}
+	for pos := 2; ; {
+		if pos+2 > len(data) || data[pos] != 0xFF {
+			return nil, errMalformedImage
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// Fill byte
+			pos++
+			continue
+		}
+		if !exifWritten && marker != 0xE0 {
+			out = append(out, exifSegment(meta)...)
+			exifWritten = true
+		}
+		// Entropy-coded data follows the start of scan
+		if marker == 0xDA || marker == 0xD9 {
+			return append(out, data[pos:]...), nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+
+		if pos+4 > len(data) {
+			return nil, errMalformedImage
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:pos+4]))
+		if end < pos+4 || end > len(data) {
+			return nil, errMalformedImage
+		}
+		switch marker {
+		case 0xE1, 0xEC, 0xED, 0xFE:
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+}
+
+// exifSegment wraps meta in a JPEG APP1 segment
+func exifSegment(meta ImageMetadata) []byte {
+	payload := append([]byte("Exif\x00\x00"), exifTIFF(meta)...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// pngMetadataChunks are the ancillary PNG chunks that carry metadata
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true, "iTXt": true, "tEXt": true, "tIME": true, "zTXt": true,
+}
+
+// stripPNG drops metadata chunks. PNG orientation isn't applied by the
+// pipeline, so nothing needs to be kept.
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if len(data) < len(signature) || string(data[:len(signature)]) != signature {
+		return nil, errMalformedImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for pos := len(signature); ; {
+		if pos+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		size := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + size
+		if size < 0 || end < pos || end > len(data) {
+			return nil, errMalformedImage
+		}
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[pos:end]...)
+		}
+		if chunkType == "IEND" {
+			return out, nil
+		}
+		pos = end
+	}
+}
+
+// stripWebP drops EXIF and XMP chunks, appending meta as a new EXIF chunk
+// to extended-format files, and updates the VP8X flags and RIFF size
+func stripWebP(data []byte, meta ImageMetadata) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformedImage
+	}
+
+	const (
+		flagXMP  = 0x04
+		flagEXIF = 0x08
+	)
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	vp8x := -1
+	for pos := 12; pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size&1
+		if size < 0 || end < pos || end > len(data) {
+			return nil, errMalformedImage
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			if size < 1 {
+				return nil, errMalformedImage
+			}
+			vp8x = len(out) + 8
+			out = append(out, data[pos:end]...)
+			out[vp8x] &^= flagXMP | flagEXIF
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	// Only the extended format can hold an EXIF chunk
+	if vp8x >= 0 && meta != (ImageMetadata{}) {
+		payload := exifTIFF(meta)
+		chunk := make([]byte, 8, 8+len(payload)+1)
+		copy(chunk, "EXIF")
+		binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+		chunk = append(chunk, payload...)
+		if len(payload)%2 == 1 {
+			chunk = append(chunk, 0)
+		}
+		out = append(out, chunk...)
+		out[vp8x] |= flagEXIF
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
+
+// EXIF tags written by exifTIFF, in ascending order as TIFF requires
+const (
+	tagModel       = 0x0110
+	tagOrientation = 0x0112
+	tagDateTime    = 0x0132
+)
+
+// exifTIFF encodes meta as a big-endian TIFF structure with a single IFD
+func exifTIFF(meta ImageMetadata) []byte {
+	type entry struct {
+		tag   uint16
+		kind  uint16
+		count uint32
+		value []byte
+	}
+	ascii := func(tag uint16, s string) entry {
+		return entry{tag: tag, kind: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+	}
+
+	var entries []entry
+	if meta.CameraModel != "" {
+		entries = append(entries, ascii(tagModel, meta.CameraModel))
+	}
+	if meta.Orientation != 0 {
+		entries = append(entries, entry{tag: tagOrientation, kind: 3, count: 1, value: []byte{0, byte(meta.Orientation)}})
+	}
+	if meta.CapturedAt != nil {
+		entries = append(entries, ascii(tagDateTime, meta.CapturedAt.Format("2006:01:02 15:04:05")))
+	}
+
+	// Header, entry count, entries and next IFD offset, then values that
+	// don't fit in an entry
+	const ifdOffset = 8
+	dataOffset := ifdOffset + 2 + 12*len(entries) + 4
+	out := make([]byte, dataOffset)
+	copy(out, "MM\x00\x2a")
+	binary.BigEndian.PutUint32(out[4:], ifdOffset)
+	binary.BigEndian.PutUint16(out[ifdOffset:], uint16(len(entries)))
+	for i, e := range entries {
+		field := out[ifdOffset+2+12*i:]
+		binary.BigEndian.PutUint16(field[0:], e.tag)
+		binary.BigEndian.PutUint16(field[2:], e.kind)
+		binary.BigEndian.PutUint32(field[4:], e.count)
+		if len(e.value) <= 4 {
+			copy(field[8:12], e.value)
+			continue
+		}
+		binary.BigEndian.PutUint32(field[8:], uint32(len(out)))
+		out = append(out, e.value...)
+		if len(out)%2 == 1 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
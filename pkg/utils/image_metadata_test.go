@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func testImage() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 7)
+	}
+	return img
+}
+
+func testMetadata() ImageMetadata {
+	capturedAt := time.Date(2024, 5, 17, 9, 30, 0, 0, time.Local)
+	return ImageMetadata{Orientation: 6, CapturedAt: &capturedAt, CameraModel: "Pixel 8"}
+}
+
+// segment builds a JPEG marker segment
+func segment(marker byte, payload string) []byte {
+	s := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+	return append(s, payload...)
+}
+
+func TestStripMetadataJPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert EXIF, XMP, IPTC and a comment after the SOI marker
+	var data []byte
+	data = append(data, encoded.Bytes()[:2]...)
+	data = append(data, exifSegment(testMetadata())...)
+	data = append(data, segment(0xE1, "http://ns.adobe.com/xap/1.0/\x00<GPSLatitude>51.5</GPSLatitude>")...)
+	data = append(data, segment(0xED, "Photoshop 3.0\x00secret-iptc")...)
+	data = append(data, segment(0xFE, "secret-comment")...)
+	data = append(data, encoded.Bytes()[2:]...)
+
+	stripped, err := StripMetadata(data, FormatJPEG)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	for _, secret := range []string{"GPSLatitude", "secret-iptc", "secret-comment"} {
+		if bytes.Contains(stripped, []byte(secret)) {
+			t.Errorf("stripped image still contains %q", secret)
+		}
+	}
+	assertMetadata(t, ReadImageMetadata(stripped), testMetadata())
+	assertDecodes(t, stripped)
+}
+
+func TestStripMetadataJPEGWithoutEXIF(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stripped, err := StripMetadata(encoded.Bytes(), FormatJPEG)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if !bytes.Equal(stripped, encoded.Bytes()) {
+		t.Error("a JPEG without metadata changed")
+	}
+}
+
+func TestStripMetadataPNG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert a text chunk after IHDR, which is 8+4+4+13+4 bytes in
+	chunk := func(chunkType, payload string) []byte {
+		c := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		c = append(c, chunkType...)
+		c = append(c, payload...)
+		return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE([]byte(chunkType+payload)))
+	}
+	const afterIHDR = 33
+	var data []byte
+	data = append(data, encoded.Bytes()[:afterIHDR]...)
+	data = append(data, chunk("tEXt", "Comment\x00secret-text")...)
+	data = append(data, encoded.Bytes()[afterIHDR:]...)
+
+	stripped, err := StripMetadata(data, FormatPNG)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if !bytes.Equal(stripped, encoded.Bytes()) {
+		t.Error("text chunk was not removed")
+	}
+}
+
+func TestStripMetadataWebP(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := EncodeWebP(&encoded, testImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewrap the simple file in the extended format with EXIF and XMP
+	riffChunk := func(fourCC string, payload []byte) []byte {
+		c := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+		c = append(c, payload...)
+		if len(payload)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	vp8x := []byte{0x0C, 0, 0, 0, 3, 0, 0, 2, 0, 0}
+	body := []byte("WEBP")
+	body = append(body, riffChunk("VP8X", vp8x)...)
+	body = append(body, encoded.Bytes()[12:]...)
+	body = append(body, riffChunk("EXIF", exifTIFF(testMetadata()))...)
+	body = append(body, riffChunk("XMP ", []byte("<GPSLatitude>51.5</GPSLatitude>"))...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	stripped, err := StripMetadata(data, FormatWebP)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if bytes.Contains(stripped, []byte("GPSLatitude")) {
+		t.Error("stripped image still contains XMP")
+	}
+	if flags := stripped[20]; flags != 0x08 {
+		t.Errorf("VP8X flags = %#x, want EXIF only", flags)
+	}
+	if size := binary.LittleEndian.Uint32(stripped[4:8]); int(size) != len(stripped)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(stripped)-8)
+	}
+	assertMetadata(t, ReadImageMetadata(stripped), testMetadata())
+	assertDecodes(t, stripped)
+}
+
+func TestStripMetadataMalformed(t *testing.T) {
+	for _, format := range []string{FormatJPEG, FormatPNG, FormatWebP} {
+		if _, err := StripMetadata([]byte("not an image"), format); err == nil {
+			t.Errorf("%s: expected an error", format)
+		}
+	}
+	truncated := append([]byte{0xFF, 0xD8}, segment(0xE1, "Exif\x00\x00")[:5]...)
+	if _, err := StripMetadata(truncated, FormatJPEG); err == nil {
+		t.Error("expected an error for a truncated JPEG segment")
+	}
+}
+
+func assertMetadata(t *testing.T, got, want ImageMetadata) {
+	t.Helper()
+	if got.Orientation != want.Orientation || got.CameraModel != want.CameraModel {
+		t.Errorf("metadata = %+v, want %+v", got, want)
+	}
+	if got.CapturedAt == nil || !got.CapturedAt.Equal(*want.CapturedAt) {
+		t.Errorf("CapturedAt = %v, want %v", got.CapturedAt, want.CapturedAt)
+	}
+}
+
+func assertDecodes(t *testing.T, data []byte) {
+	t.Helper()
+	img, _, err := DecodeImage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("stripped image doesn't decode: %v", err)
+	}
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 3 {
+		t.Errorf("bounds = %v, want 4x3", img.Bounds())
+	}
+}
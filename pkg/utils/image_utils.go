@@ -2,13 +2,28 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
+	_ "image/gif"
 	"image/jpeg"
 	"image/png"
-	"net/http"
+	"io"
+	"math"
+	"strings"
 
 	"github.com/nfnt/resize"
+	_ "golang.org/x/image/webp"
+)
+
+// Image formats recognised by DecodeImage. EncodeImage writes all of them
+// except GIF.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWebP = "webp"
 )
 
 // ImageProcessor provides utility functions for image processing
@@ -16,67 +31,76 @@ type ImageProcessor struct {
 	MaxWidth  uint
 	MaxHeight uint
 	Quality   int
+	Fetcher   *Fetcher
 }
 
 // NewImageProcessor creates a new ImageProcessor with default settings
 func NewImageProcessor() *ImageProcessor {
+	// The default config has no allowed hosts, so it can't fail
+	fetcher, _ := NewFetcher(DefaultFetcherConfig())
+
 	return &ImageProcessor{
 		MaxWidth:  800,
 		MaxHeight: 600,
 		Quality:   75,
+		Fetcher:   fetcher,
 	}
 }
 
 // DownloadImage downloads an image from a given URL
 func (ip *ImageProcessor) DownloadImage(url string) (image.Image, string, error) {
 	// Download the image
-	resp, err := http.Get(url)
+	data, err := ip.Fetcher.FetchImage(context.Background(), url)
 	if err != nil {
 		return nil, "", err
 	}
-	defer resp.Body.Close()
-
-	// Determine image format
-	contentType := resp.Header.Get("Content-Type")
-
-	// Decode the image
-	var img image.Image
-	switch contentType {
-	case "image/jpeg":
-		img, err = jpeg.Decode(resp.Body)
-	case "image/png":
-		img, err = png.Decode(resp.Body)
-	default:
-		return nil, "", fmt.Errorf("unsupported image type: %s", contentType)
-	}
 
+	// Decode the image, trusting its content rather than the Content-Type
+	img, format, err := DecodeImage(bytes.NewReader(data))
 	if err != nil {
 		return nil, "", err
 	}
 
-	return img, contentType, nil
+	return Orient(img, ReadImageMetadata(data).Orientation), "image/" + format, nil
 }
 
-// ResizeImage resizes an image maintaining aspect ratio
-func (ip *ImageProcessor) ResizeImage(img image.Image) image.Image {
-	// Calculate new dimensions
-	width := img.Bounds().Dx()
-	height := img.Bounds().Dy()
-
-	// Determine scaling
-	var newWidth, newHeight uint
-	if width > height {
-		newWidth = ip.MaxWidth
-		ratio := float64(height) / float64(width)
-		newHeight = uint(float64(newWidth) * ratio)
-	} else {
-		newHeight = ip.MaxHeight
-		ratio := float64(width) / float64(height)
-		newWidth = uint(float64(newHeight) * ratio)
+// DecodeImage decodes a JPEG, PNG, GIF or WebP image, detecting the format
+// from its magic bytes. Animated GIFs decode to their first frame.
+func DecodeImage(r io.Reader) (image.Image, string, error) {
+	img, format, err := image.Decode(r)
+	if errors.Is(err, image.ErrFormat) {
+		return nil, "", errors.New("unsupported image format")
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s image: %w", format, err)
 	}
+	return img, format, nil
+}
 
-	// Resize the image
-	return resize.Resize(newWidth, newHeight, img, resize.Lanczos3)
+// OutputFormat returns the format a source image is re-encoded in when no
+// format is requested. GIFs become PNGs since re-quantizing resized frames
+// to a 256-colour palette degrades them badly.
+func OutputFormat(sourceFormat string) string {
+	if sourceFormat == FormatGIF {
+		return FormatPNG
+	}
+	return sourceFormat
+}
+
+// WebPFallbackFormat returns the format used instead of WebP for images
+// larger than MaxWebPDimension: the source format, or PNG when that is
+// WebP or GIF
+func WebPFallbackFormat(sourceFormat string) string {
+	if format := OutputFormat(sourceFormat); format != FormatWebP {
+		return format
+	}
+	return FormatPNG
+}
+
+// ResizeImage resizes an image to fit within MaxWidth x MaxHeight
+// maintaining aspect ratio
+func (ip *ImageProcessor) ResizeImage(img image.Image) image.Image {
+	return FitImage(img, ip.MaxWidth, ip.MaxHeight, FitContain)
 }
 
 // CompressImage compresses the image to a byte slice
@@ -84,23 +108,84 @@ func (ip *ImageProcessor) CompressImage(img image.Image, format string) ([]byte,
 	// Create a buffer to store the compressed image
 	buf := new(bytes.Buffer)
 
-	// Compress based on format
-	switch format {
-	case "image/jpeg":
-		err := jpeg.Encode(buf, img, &jpeg.Options{Quality: ip.Quality})
-		if err != nil {
-			return nil, err
+	format = OutputFormat(strings.TrimPrefix(format, "image/"))
+	if err := EncodeImage(buf, img, format, ip.Quality); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Fit modes for FitImage
+const (
+	// FitContain scales the image to fit inside the box
+	FitContain = "contain"
+	// FitCover scales the image to fill the box and crops the overflow
+	FitCover = "cover"
+)
+
+// FitImage scales img into a width x height box without upscaling. A zero
+// width or height leaves that dimension unconstrained; if both are zero the
+// image is returned unchanged.
+func FitImage(img image.Image, width, height uint, fit string) image.Image {
+	srcWidth := uint(img.Bounds().Dx())
+	srcHeight := uint(img.Bounds().Dy())
+
+	if width == 0 && height == 0 {
+		return img
+	}
+
+	if fit != FitCover || width == 0 || height == 0 {
+		if width == 0 || width > srcWidth {
+			width = srcWidth
 		}
-	case "image/png":
-		err := png.Encode(buf, img)
-		if err != nil {
-			return nil, err
+		if height == 0 || height > srcHeight {
+			height = srcHeight
 		}
-	default:
-		return nil, fmt.Errorf("unsupported image format: %s", format)
+		return resize.Thumbnail(width, height, img, resize.Lanczos3)
 	}
 
-	return buf.Bytes(), nil
+	// Cover: don't upscale, so shrink the box if the source is smaller
+	scale := math.Max(float64(width)/float64(srcWidth), float64(height)/float64(srcHeight))
+	if scale > 1 {
+		width = uint(float64(width) / scale)
+		height = uint(float64(height) / scale)
+		scale = 1
+	}
+
+	scaled := resize.Resize(
+		uint(math.Ceil(float64(srcWidth)*scale)),
+		uint(math.Ceil(float64(srcHeight)*scale)),
+		img, resize.Lanczos3,
+	)
+
+	// Crop the centre of the scaled image
+	bounds := scaled.Bounds()
+	x := bounds.Min.X + (bounds.Dx()-int(width))/2
+	y := bounds.Min.Y + (bounds.Dy()-int(height))/2
+	crop := image.Rect(x, y, x+int(width), y+int(height))
+
+	if sub, ok := scaled.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(crop)
+	}
+	return scaled
+}
+
+// EncodeImage writes img as JPEG, PNG or WebP. Quality applies to JPEG
+// only; WebP output is lossless.
+func EncodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatWebP:
+		return EncodeWebP(w, img)
+	default:
+		return fmt.Errorf("unsupported image format: %s", format)
+	}
 }
 
 // ProcessImage combines download, resize, and compression
@@ -0,0 +1,365 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// VP8L (lossless WebP) bitstream constants, see RFC 9649
+const (
+	vp8lSignature        = 0x2f
+	vp8lMaxDimension     = 1 << 14
+	vp8lMaxCodeLength    = 15
+	vp8lMaxCLCodeLength  = 7
+	vp8lSubtractGreen    = 2
+	vp8lGreenAlphabet    = 256 + 24
+	vp8lLiteralAlphabet  = 256
+	vp8lDistanceAlphabet = 40
+)
+
+// vp8lCodeLengthCodeOrder is the order code length code lengths are written in
+var vp8lCodeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// MaxWebPDimension is the largest width or height a WebP image can have
+const MaxWebPDimension = vp8lMaxDimension
+
+var errWebPTooLarge = errors.New("webp: image dimensions exceed 16384 pixels")
+
+// EncodeWebP writes img as a lossless WebP (VP8L) image. It applies the
+// subtract-green transform and entropy-codes literal pixels, which keeps the
+// encoder small and dependency-free at the cost of larger files than a
+// lossy encoder would produce.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return errWebPTooLarge
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || nrgba.Rect.Min != (image.Point{}) {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	// Apply subtract-green and gather symbol frequencies
+	pixels := make([][4]uint8, 0, width*height)
+	var greenFreq [vp8lGreenAlphabet]uint32
+	var redFreq, blueFreq, alphaFreq [vp8lLiteralAlphabet]uint32
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+4*width]
+		for x := 0; x < width; x++ {
+			r, g, b, a := row[4*x], row[4*x+1], row[4*x+2], row[4*x+3]
+			r, b = r-g, b-g
+			pixels = append(pixels, [4]uint8{g, r, b, a})
+			greenFreq[g]++
+			redFreq[r]++
+			blueFreq[b]++
+			alphaFreq[a]++
+			if a != 0xff {
+				hasAlpha = true
+			}
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // version
+
+	// One subtract-green transform, then no more transforms
+	bw.writeBits(1, 1)
+	bw.writeBits(vp8lSubtractGreen, 2)
+	bw.writeBits(0, 1)
+
+	bw.writeBits(0, 1) // no color cache
+	bw.writeBits(0, 1) // no meta prefix codes
+
+	green := writePrefixCode(bw, greenFreq[:])
+	red := writePrefixCode(bw, redFreq[:])
+	blue := writePrefixCode(bw, blueFreq[:])
+	alpha := writePrefixCode(bw, alphaFreq[:])
+	writePrefixCode(bw, make([]uint32, vp8lDistanceAlphabet))
+
+	for _, p := range pixels {
+		green.write(bw, int(p[0]))
+		red.write(bw, int(p[1]))
+		blue.write(bw, int(p[2]))
+		alpha.write(bw, int(p[3]))
+	}
+
+	return writeRIFF(w, bw.bytes())
+}
+
+func writeRIFF(w io.Writer, data []byte) error {
+	padded := len(data) + len(data)&1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+padded))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padded != len(data) {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// bitWriter packs values least-significant bit first, as VP8L expects
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint
+}
+
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nBits
+	w.nBits += n
+	for w.nBits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nBits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nBits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nBits = 0, 0
+	}
+	return w.buf
+}
+
+// prefixCode maps symbols to canonical Huffman codes. Codes are stored
+// bit-reversed so they can be written LSB first.
+type prefixCode struct {
+	codes   []uint32
+	lengths []uint32
+}
+
+func (c *prefixCode) write(w *bitWriter, symbol int) {
+	if n := c.lengths[symbol]; n > 0 {
+		w.writeBits(c.codes[symbol], uint(n))
+	}
+}
+
+// writePrefixCode chooses and writes a prefix code for the given symbol
+// frequencies and returns it for encoding symbols
+func writePrefixCode(w *bitWriter, freq []uint32) *prefixCode {
+	var used []int
+	for symbol, f := range freq {
+		if f > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	// Simple codes cover up to two 8-bit symbols and need no code lengths
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		w.writeBits(1, 1)
+		w.writeBits(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			w.writeBits(0, 1)
+			w.writeBits(uint32(used[0]), 1)
+		} else {
+			w.writeBits(1, 1)
+			w.writeBits(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			w.writeBits(uint32(used[1]), 8)
+		}
+
+		code := &prefixCode{codes: make([]uint32, len(freq)), lengths: make([]uint32, len(freq))}
+		if len(used) == 2 {
+			code.codes[used[1]] = 1
+			code.lengths[used[0]] = 1
+			code.lengths[used[1]] = 1
+		}
+		return code
+	}
+
+	lengths := huffmanCodeLengths(freq, vp8lMaxCodeLength)
+
+	// Code lengths are themselves Huffman coded, using only the literal
+	// length symbols 0-15
+	clFreq := make([]uint32, len(vp8lCodeLengthCodeOrder))
+	for _, l := range lengths {
+		clFreq[l]++
+	}
+	clLengths := huffmanCodeLengths(clFreq, vp8lMaxCLCodeLength)
+	clCode := newPrefixCode(clLengths)
+
+	numCodes := 4
+	for i, symbol := range vp8lCodeLengthCodeOrder {
+		if clLengths[symbol] > 0 && i+1 > numCodes {
+			numCodes = i + 1
+		}
+	}
+
+	w.writeBits(0, 1)
+	w.writeBits(uint32(numCodes-4), 4)
+	for _, symbol := range vp8lCodeLengthCodeOrder[:numCodes] {
+		w.writeBits(clLengths[symbol], 3)
+	}
+	w.writeBits(0, 1) // code lengths for the whole alphabet follow
+	for _, l := range lengths {
+		clCode.write(w, int(l))
+	}
+
+	return newPrefixCode(lengths)
+}
+
+// newPrefixCode assigns canonical codes to the given code lengths. A single
+// used symbol gets a zero-length code, matching how decoders read it.
+func newPrefixCode(lengths []uint32) *prefixCode {
+	code := &prefixCode{codes: make([]uint32, len(lengths)), lengths: make([]uint32, len(lengths))}
+
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	if used <= 1 {
+		return code
+	}
+
+	var histogram [vp8lMaxCodeLength + 1]uint32
+	for _, l := range lengths {
+		histogram[l]++
+	}
+	histogram[0] = 0
+
+	var nextCode [vp8lMaxCodeLength + 1]uint32
+	next := uint32(0)
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		next = (next + histogram[l-1]) << 1
+		nextCode[l] = next
+	}
+
+	for symbol, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		code.codes[symbol] = reverseBits(nextCode[l], l)
+		code.lengths[symbol] = l
+		nextCode[l]++
+	}
+	return code
+}
+
+func reverseBits(v uint32, n uint32) uint32 {
+	var r uint32
+	for i := uint32(0); i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+// huffmanCodeLengths builds Huffman code lengths no longer than maxLength.
+// If the optimal tree is too deep, small frequencies are raised until it
+// fits, trading a little compression for a valid code.
+func huffmanCodeLengths(freq []uint32, maxLength uint32) []uint32 {
+	lengths := make([]uint32, len(freq))
+
+	var used []int
+	for symbol, f := range freq {
+		if f > 0 {
+			used = append(used, symbol)
+		}
+	}
+	switch len(used) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[used[0]] = 1
+		return lengths
+	}
+
+	for minFreq := uint32(1); ; minFreq *= 2 {
+		adjusted := make([]uint32, len(used))
+		for i, symbol := range used {
+			adjusted[i] = max(freq[symbol], minFreq)
+		}
+
+		depths := huffmanDepths(adjusted)
+		maxDepth := uint32(0)
+		for _, d := range depths {
+			maxDepth = max(maxDepth, d)
+		}
+		if maxDepth <= maxLength {
+			for i, symbol := range used {
+				lengths[symbol] = depths[i]
+			}
+			return lengths
+		}
+	}
+}
+
+// huffmanDepths returns the depth of each leaf in a Huffman tree built from
+// freq, using the two-queue construction over sorted leaves
+func huffmanDepths(freq []uint32) []uint32 {
+	n := len(freq)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return freq[order[a]] < freq[order[b]] })
+
+	// Nodes 0..n-1 are leaves in sorted order, n.. are internal nodes
+	weights := make([]uint64, 0, 2*n-1)
+	for _, i := range order {
+		weights = append(weights, uint64(freq[i]))
+	}
+	parents := make([]int, 2*n-1)
+
+	leaf, internal := 0, n
+	pop := func() int {
+		if leaf < n && (internal >= len(weights) || weights[leaf] <= weights[internal]) {
+			leaf++
+			return leaf - 1
+		}
+		internal++
+		return internal - 1
+	}
+
+	for len(weights) < 2*n-1 {
+		a, b := pop(), pop()
+		parents[a] = len(weights)
+		parents[b] = len(weights)
+		weights = append(weights, weights[a]+weights[b])
+	}
+
+	// Parents always come after their children, so walk backwards from the root
+	nodeDepth := make([]uint32, 2*n-1)
+	for i := 2*n - 3; i >= 0; i-- {
+		nodeDepth[i] = nodeDepth[parents[i]] + 1
+	}
+
+	depths := make([]uint32, n)
+	for sorted, original := range order {
+		depths[original] = nodeDepth[sorted]
+	}
+	return depths
+}